@@ -22,12 +22,12 @@
 
 package velocypack
 
-import "strconv"
+var attributeTranslator AttributeTranslator = &arangoAttributeIDTranslator{}
 
-var attributeTranslator attributeIDTranslator = &arangoAttributeIDTranslator{}
-
-// attributeIDTranslator is used to translation integer style object keys to strings.
-type attributeIDTranslator interface {
+// AttributeTranslator is used to translate integer style object keys to strings.
+type AttributeTranslator interface {
+	// IDToString returns the attribute name for the given ID,
+	// or an empty string if the ID is unknown.
 	IDToString(id uint64) string
 }
 
@@ -46,6 +46,6 @@ func (t *arangoAttributeIDTranslator) IDToString(id uint64) string {
 	case 5:
 		return "_to"
 	default:
-		return strconv.FormatUint(id, 10)
+		return ""
 	}
 }
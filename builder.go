@@ -35,15 +35,39 @@ type BuilderOptions struct {
 	BuildUnindexedArrays     bool
 	BuildUnindexedObjects    bool
 	CheckAttributeUniqueness bool
+	// If set, objects with an index table are built with an unsorted index table
+	// (head bytes 0x0f-0x12), which avoids sorting the attributes when closing the object.
+	// Looking up attributes in such objects uses a linear search.
+	BuildUnsortedObjects bool
+	// MaxDepth is the maximum nesting depth of arrays and objects.
+	// Opening an array or object beyond this depth results in a BuilderMaxDepthExceededError.
+	// A maximum depth of 0 means no limit.
+	MaxDepth int
+	// MaxSize is the maximum number of bytes generated by the builder.
+	// Adding a value or closing an array or object that makes the generated bytes
+	// exceed this size results in a BuilderMaxSizeExceededError.
+	// After such an error the builder must be cleared or reset before it is used again.
+	// A maximum size of 0 means no limit.
+	MaxSize ValueLength
+	// If set, the builder keeps a set of the keys of each open object once a key is looked up in it.
+	// This makes the cost of HasKey and GetKey independent of the number of keys in the object,
+	// at the cost of copying each key of the object into the set.
+	// Removing entries (RemoveKey, Rollback or a failed add) discards the set,
+	// so it is rebuilt on the next lookup.
+	// The set is not used by Close, so it only pays off when keys of large objects are looked up repeatedly.
+	IndexKeys bool
 }
 
 // Builder is used to build VPack structures.
 type Builder struct {
 	BuilderOptions
 	buf        builderBuffer
+	fixed      []byte // Caller provided buffer, see NewBuilderWithBuffer
 	stack      builderStack
 	index      []indexVector
+	keySets    []keySet // Keys of open objects, only used when IndexKeys is set
 	keyWritten bool
+	generation uint64 // Incremented by Clear, Reset, RemoveKey and ReplaceKey to invalidate checkpoints
 }
 
 func NewBuilder(capacity uint) *Builder {
@@ -53,11 +77,53 @@ func NewBuilder(capacity uint) *Builder {
 	return b
 }
 
+// NewBuilderWithBuffer creates a builder that writes the generated bytes into the given buffer,
+// starting at its beginning and limited by its capacity.
+// The bytes returned by Bytes and Slice are stored in this buffer.
+// Adding a value or closing an array or object that makes the generated bytes
+// exceed the capacity of the buffer results in a BuilderBufferFullError.
+// This is detected before writing, so the builder never allocates a larger buffer.
+// Note that each open array or object reserves 9 bytes for its header until it is closed,
+// so the buffer must be larger than the result while building nested values.
+// After such an error the builder must be cleared or reset before it is used again.
+//
+// There is no chunked buffer variant: closing an array or object moves its content
+// and Bytes and Slice return the result as a single slice, so the generated bytes
+// are always kept in one contiguous buffer.
+func NewBuilderWithBuffer(buf []byte) *Builder {
+	b := &Builder{
+		buf:   buf[:0],
+		fixed: buf[:0],
+	}
+	return b
+}
+
 // Clear and start from scratch:
 func (b *Builder) Clear() {
 	b.buf = nil
+	if b.fixed != nil {
+		b.buf = b.fixed
+	}
 	b.stack.Clear()
 	b.keyWritten = false
+	b.generation++
+}
+
+// Reset starts from scratch, like Clear, but keeps the allocated buffer
+// and index vectors so they can be reused.
+// Slices previously obtained from the builder are overwritten by subsequent
+// modifications, so they must not be used after calling Reset.
+func (b *Builder) Reset() {
+	b.buf = b.buf[:0]
+	if b.fixed != nil {
+		b.buf = b.fixed
+	}
+	b.stack.Reset()
+	for i := range b.index {
+		b.index[i].Clear()
+	}
+	b.keyWritten = false
+	b.generation++
 }
 
 // Bytes return the generated bytes.
@@ -152,6 +218,14 @@ func (b *Builder) OpenArray(unindexed ...bool) error {
 
 // Close ends an open object or array.
 func (b *Builder) Close() error {
+	if err := b.close(); err != nil {
+		return WithStack(err)
+	}
+	return WithStack(b.checkMaxSize())
+}
+
+// close ends an open object or array.
+func (b *Builder) close() error {
 	if b.IsClosed() {
 		return WithStack(BuilderNeedOpenCompoundError)
 	}
@@ -175,21 +249,27 @@ func (b *Builder) Close() error {
 	if head == 0x13 || head == 0x14 ||
 		(head == 0x06 && b.BuilderOptions.BuildUnindexedArrays) ||
 		(head == 0x0b && (b.BuilderOptions.BuildUnindexedObjects || len(index) == 1)) {
-		if b.closeCompactArrayOrObject(tos, isArray, index) {
+		if closed, err := b.closeCompactArrayOrObject(tos, isArray, index); err != nil {
+			return WithStack(err)
+		} else if closed {
 			return nil
 		}
 		// This might fall through, if closeCompactArrayOrObject gave up!
 	}
 
 	if isArray {
-		b.closeArray(tos, index)
-		return nil
+		return WithStack(b.closeArray(tos, index))
 	}
 
 	// From now on we're closing an object
 
 	// fix head byte in case a compact Array / Object was originally requested
-	b.buf[tos] = 0x0b
+	sorted := !b.BuilderOptions.BuildUnsortedObjects
+	if sorted {
+		b.buf[tos] = 0x0b
+	} else {
+		b.buf[tos] = 0x0f
+	}
 
 	// First determine byte length and its format:
 	offsetSize := uint(8)
@@ -201,7 +281,27 @@ func (b *Builder) Close() error {
 		// case we would win back 6 bytes but would need one byte per subvalue
 		// for the index table
 		offsetSize = 1
+	} else if b.buf.Len()-tos+2*ValueLength(len(index)) <= 0xffff {
+		offsetSize = 2
+	} else if b.buf.Len()-tos+4*ValueLength(len(index)) <= 0xffffffff {
+		offsetSize = 4
+	}
 
+	// Check that the table fits before changing anything.
+	// In the 1-byte case, 6 of the reserved bytes are given back first.
+	extraSpace := offsetSize * uint(len(index))
+	if offsetSize == 8 {
+		extraSpace += 8
+	}
+	shrink := uint(0)
+	if offsetSize == 1 {
+		shrink = 6
+	}
+	if err := b.checkFixedGrowth(extraSpace, shrink); err != nil {
+		return WithStack(err)
+	}
+
+	if offsetSize == 1 {
 		// Maybe we need to move down data:
 		targetPos := ValueLength(3)
 		if b.buf.Len() > (tos + 9) {
@@ -220,22 +320,14 @@ func (b *Builder) Close() error {
 		// One could move down things in the offsetSize == 2 case as well,
 		// since we only need 4 bytes in the beginning. However, saving these
 		// 4 bytes has been sacrificed on the Altar of Performance.
-	} else if b.buf.Len()-tos+2*ValueLength(len(index)) <= 0xffff {
-		offsetSize = 2
-	} else if b.buf.Len()-tos+4*ValueLength(len(index)) <= 0xffffffff {
-		offsetSize = 4
 	}
 
 	// Now build the table:
-	extraSpace := offsetSize * uint(len(index))
-	if offsetSize == 8 {
-		extraSpace += 8
-	}
 	b.buf.ReserveSpace(extraSpace)
 	tableBase := b.buf.Len()
 	b.buf.Grow(offsetSize * uint(len(index)))
 	// Object
-	if len(index) >= 2 {
+	if sorted && len(index) >= 2 {
 		if err := b.sortObjectIndex(b.buf[tos:], index); err != nil {
 			return WithStack(err)
 		}
@@ -296,65 +388,68 @@ func (b *Builder) IsClosed() bool {
 
 // HasKey checks whether an Object value has a specific key attribute.
 func (b *Builder) HasKey(key string) (bool, error) {
-	if b.stack.IsEmpty() {
-		return false, WithStack(BuilderNeedOpenObjectError)
-	}
-	tos, _ := b.stack.Tos()
-	h := b.buf[tos]
-	if h != 0x0b && h != 0x14 {
+	if !b.IsOpenObject() {
 		return false, WithStack(BuilderNeedOpenObjectError)
 	}
-	index := b.index[b.stack.Len()-1]
-	if index.IsEmpty() {
-		return false, nil
-	}
-	for _, idx := range index {
-		s := Slice(b.buf[tos+idx:])
-		k, err := s.makeKey()
-		if err != nil {
-			return false, WithStack(err)
-		}
-		if eq, err := k.IsEqualString(key); err != nil {
-			return false, WithStack(err)
-		} else if eq {
-			return true, nil
-		}
+	pos, err := b.findKey(key)
+	if err != nil {
+		return false, WithStack(err)
 	}
-	return false, nil
+	return pos >= 0, nil
 }
 
 // GetKey returns the value for a specific key of an Object value.
 // Returns Slice of type None when key is not found.
 func (b *Builder) GetKey(key string) (Slice, error) {
-	if b.stack.IsEmpty() {
+	if !b.IsOpenObject() {
 		return nil, WithStack(BuilderNeedOpenObjectError)
 	}
-	tos, _ := b.stack.Tos()
-	h := b.buf[tos]
-	if h != 0x0b && h != 0x14 {
-		return nil, WithStack(BuilderNeedOpenObjectError)
+	pos, err := b.findKey(key)
+	if err != nil {
+		return nil, WithStack(err)
+	} else if pos < 0 {
+		return nil, nil
 	}
-	index := b.index[b.stack.Len()-1]
+	tos, stackLen := b.stack.Tos()
+	value, err := Slice(b.buf[tos+b.index[stackLen-1][pos]:]).Next()
+	if err != nil {
+		return nil, WithStack(err)
+	}
+	return value, nil
+}
+
+// findKey returns the position in the index vector of the first occurrence
+// of the given key in the open object at the top of the stack.
+// Returns -1 when the key is not found.
+func (b *Builder) findKey(key string) (int, error) {
+	tos, stackLen := b.stack.Tos()
+	depth := stackLen - 1
+	index := b.index[depth]
 	if index.IsEmpty() {
-		return nil, nil
+		return -1, nil
+	}
+	if b.IndexKeys {
+		ks, err := b.keySet(depth)
+		if err != nil {
+			return -1, WithStack(err)
+		}
+		if pos, found := ks.keys[key]; found {
+			return pos, nil
+		}
+		return -1, nil
 	}
-	for _, idx := range index {
-		s := Slice(b.buf[tos+idx:])
-		k, err := s.makeKey()
+	for pos, idx := range index {
+		k, err := Slice(b.buf[tos+idx:]).makeKey()
 		if err != nil {
-			return nil, WithStack(err)
+			return -1, WithStack(err)
 		}
 		if eq, err := k.IsEqualString(key); err != nil {
-			return nil, WithStack(err)
+			return -1, WithStack(err)
 		} else if eq {
-			value, err := s.Next()
-			if err != nil {
-				return nil, WithStack(err)
-			}
-			return value, nil
+			return pos, nil
 		}
 	}
-	return nil, nil
+	return -1, nil
 }
 
 // RemoveLast removes last subvalue written to an (unclosed) object or array.
@@ -371,9 +466,117 @@ func (b *Builder) RemoveLast() error {
 	lastSize := b.buf.Len() - newLength
 	b.buf.Shrink(uint(lastSize))
 	index.RemoveLast()
+	b.invalidateKeySet(b.stack.Len() - 1)
+	return nil
+}
+
+// RemoveKey removes the given key and its value from the open object at the top of the stack.
+// If the key occurs more than once, only its first occurrence is removed.
+// Returns a BuilderKeyNotFoundError when the object does not contain the key.
+// Checkpoints created before the call can no longer be used.
+func (b *Builder) RemoveKey(key string) error {
+	pos, err := b.findExistingKey(key)
+	if err != nil {
+		return WithStack(err)
+	}
+	tos, stackLen := b.stack.Tos()
+	index := &b.index[stackLen-1]
+	start := tos + (*index)[pos]
+	end := b.entryEnd(pos)
+	copy(b.buf[start:], b.buf[end:])
+	b.buf.Shrink(uint(end - start))
+	*index = append((*index)[:pos], (*index)[pos+1:]...)
+	for i := pos; i < len(*index); i++ {
+		(*index)[i] -= end - start
+	}
+	b.invalidateKeySet(stackLen - 1)
+	b.generation++
+	return nil
+}
+
+// ReplaceKey replaces the value of the given key in the open object at the top of the stack.
+// If the key occurs more than once, only the value of its first occurrence is replaced.
+// The given value cannot be an array or object that is opened by this call,
+// but it can be a slice containing a complete array or object.
+// Returns a BuilderKeyNotFoundError when the object does not contain the key.
+// Checkpoints created before the call can no longer be used.
+func (b *Builder) ReplaceKey(key string, v Value) error {
+	if !v.IsSlice() && (v.vt == Array || v.vt == Object) {
+		return WithStack(BuilderUnexpectedTypeError{"Cannot replace a value with an open Array or Object"})
+	}
+	pos, err := b.findExistingKey(key)
+	if err != nil {
+		return WithStack(err)
+	}
+	tos, stackLen := b.stack.Tos()
+	index := b.index[stackLen-1]
+	keySize, err := Slice(b.buf[tos+index[pos]:]).ByteSize()
+	if err != nil {
+		return WithStack(err)
+	}
+	start := tos + index[pos] + keySize
+	end := b.entryEnd(pos)
+
+	// Keep a copy of the old value and all entries after it
+	tail := append([]byte(nil), b.buf[start:]...)
+	restore := func() {
+		b.buf = b.buf[:start]
+		b.buf.Write(tail)
+		b.keyWritten = false
+	}
+	b.buf = b.buf[:start]
+	b.keyWritten = true
+	if err := b.set(v); err != nil {
+		restore()
+		return WithStack(err)
+	}
+	newEnd := b.buf.Len()
+	if err := b.checkFixedSpace(ValueLength(len(tail)) - (end - start)); err != nil {
+		restore()
+		return WithStack(err)
+	}
+	b.buf.Write(tail[end-start:])
+	if err := b.checkMaxSize(); err != nil {
+		restore()
+		return WithStack(err)
+	}
+	for i := pos + 1; i < len(index); i++ {
+		index[i] = index[i] - end + newEnd
+	}
+	b.generation++
 	return nil
 }
 
+// findExistingKey returns the position in the index vector of the first occurrence
+// of the given key in the open object at the top of the stack.
+// Returns a BuilderKeyNotFoundError when the key is not found.
+func (b *Builder) findExistingKey(key string) (int, error) {
+	if !b.IsOpenObject() {
+		return -1, WithStack(BuilderNeedOpenObjectError)
+	}
+	if b.keyWritten {
+		return -1, WithStack(BuilderKeyAlreadyWrittenError)
+	}
+	pos, err := b.findKey(key)
+	if err != nil {
+		return -1, WithStack(err)
+	} else if pos < 0 {
+		return -1, WithStack(BuilderKeyNotFoundError)
+	}
+	return pos, nil
+}
+
+// entryEnd returns the position in the buffer directly after the entry
+// at the given position in the index vector of the array or object at the top of the stack.
+func (b *Builder) entryEnd(pos int) ValueLength {
+	tos, stackLen := b.stack.Tos()
+	index := b.index[stackLen-1]
+	if pos+1 < len(index) {
+		return tos + index[pos+1]
+	}
+	return b.buf.Len()
+}
+
 // addNull adds a null value to the buffer.
 func (b *Builder) addNull() {
 	b.buf.WriteByte(0x18)
@@ -453,7 +656,7 @@ func (b *Builder) addString(v string) {
 // addBinary adds a binary value to the buffer.
 func (b *Builder) addBinary(v []byte) {
 	l := uint(len(v))
-	b.buf.ReserveSpace(1 + 8 + l)
+	b.buf.ReserveSpace(uint(binaryByteSize(v)))
 	b.appendUInt(uint64(l), 0xbf) // data length
 	b.buf.Write(v)                // data
 }
@@ -473,6 +676,56 @@ func (b *Builder) addMaxKey() {
 	b.buf.WriteByte(0x1f)
 }
 
+// intByteSize returns the number of bytes added by addInt.
+func intByteSize(v int64) ValueLength {
+	if v >= -6 && v <= 9 {
+		return 1
+	}
+	return ValueLength(1 + intLength(v))
+}
+
+// uintByteSize returns the number of bytes added by addUInt.
+func uintByteSize(v uint64) ValueLength {
+	if v <= 9 {
+		return 1
+	}
+	return ValueLength(1 + uintLength(v))
+}
+
+// stringByteSize returns the number of bytes added by addString.
+func stringByteSize(v string) ValueLength {
+	if len(v) > 126 {
+		return ValueLength(1 + 8 + len(v))
+	}
+	return ValueLength(1 + len(v))
+}
+
+// binaryByteSize returns the number of bytes added by addBinary.
+func binaryByteSize(v []byte) ValueLength {
+	l := uint64(len(v))
+	return ValueLength(1 + uintLength(l) + uint(l))
+}
+
+// valueByteSize returns the number of bytes added by set for the given value,
+// which is not a slice. An array or object counts with its reserved header.
+func valueByteSize(item Value) ValueLength {
+	switch item.vt {
+	case Null, Bool, Illegal, MinKey, MaxKey:
+		return 1
+	case Double, UTCDate, Array, Object:
+		return 9
+	case SmallInt, Int:
+		return intByteSize(item.intValue())
+	case UInt:
+		return uintByteSize(item.uintValue())
+	case String:
+		return stringByteSize(item.stringValue())
+	case Binary:
+		return binaryByteSize(item.binaryValue())
+	}
+	return 0
+}
+
 // Add adds a raw go value value to an array/raw value/object.
 func (b *Builder) Add(v interface{}) error {
 	if it, ok := v.(*ObjectIterator); ok {
@@ -570,6 +823,16 @@ func (b *Builder) AddKeyValuesFromIterator(it *ObjectIterator) error {
 	return nil
 }
 
+// returns number of bytes required to store the unsigned value (at least 1)
+func uintLength(value uint64) uint {
+	vSize := uint(1)
+	for value > 0xff {
+		vSize++
+		value >>= 8
+	}
+	return vSize
+}
+
 // returns number of bytes required to store the value in 2s-complement
 func intLength(value int64) uint {
 	if value >= -0x80 && value <= 0x7f {
@@ -618,19 +881,13 @@ func (b *Builder) appendInt(v int64, base uint) {
 }
 
 func (b *Builder) appendUInt(v uint64, base uint) {
-	b.buf.ReserveSpace(9)
-	save := b.buf.Len()
-	b.buf.WriteByte(0) // Will be overwritten at end of function.
-	vSize := uint(0)
-	for {
-		vSize++
-		b.buf.WriteByte(byte(v & 0xff))
+	vSize := uintLength(v)
+	dst := b.buf.Grow(1 + vSize)
+	dst[0] = byte(base + vSize)
+	for i := uint(1); i <= vSize; i++ {
+		dst[i] = byte(v & 0xff)
 		v >>= 8
-		if v == 0 {
-			break
-		}
 	}
-	b.buf[save] = byte(base + vSize)
 }
 
 func (b *Builder) appendLength(v ValueLength, n uint) {
@@ -648,6 +905,12 @@ func setLength(dst []byte, v ValueLength, n uint) {
 // openCompoundValue opens an array/object, checking the context.
 func (b *Builder) openCompoundValue(vType byte) error {
 	//haveReported := false
+	if err := b.checkMaxDepth(); err != nil {
+		return WithStack(err)
+	}
+	if err := b.checkFixedSpace(9); err != nil {
+		return WithStack(err)
+	}
 	tos, stackLen := b.stack.Tos()
 	if stackLen > 0 {
 		h := b.buf[tos]
@@ -663,6 +926,55 @@ func (b *Builder) openCompoundValue(vType byte) error {
 	}
 	b.addCompoundValue(vType)
 	// if err && haveReported { b.cleanupAdd() }
+	return WithStack(b.checkMaxSize())
+}
+
+// checkMaxDepth returns an error if opening another array or object would exceed MaxDepth.
+func (b *Builder) checkMaxDepth() error {
+	if b.MaxDepth > 0 && b.stack.Len() >= b.MaxDepth {
+		return WithStack(BuilderMaxDepthExceededError)
+	}
+	return nil
+}
+
+// checkMaxSize returns an error if the generated bytes exceed MaxSize
+// or do not fit in the buffer given to NewBuilderWithBuffer.
+func (b *Builder) checkMaxSize() error {
+	if b.MaxSize > 0 && b.buf.Len() > b.MaxSize {
+		return WithStack(BuilderMaxSizeExceededError)
+	}
+	return WithStack(b.checkFixedBuffer())
+}
+
+// checkFixedSpace returns an error if adding n bytes makes the generated bytes exceed
+// the capacity of the buffer given to NewBuilderWithBuffer.
+// It is called before writing, so the buffer is not grown when the bytes do not fit.
+func (b *Builder) checkFixedSpace(n ValueLength) error {
+	if b.fixed != nil && b.buf.Len()+n > ValueLength(cap(b.fixed)) {
+		return WithStack(BuilderBufferFullError)
+	}
+	return nil
+}
+
+// checkFixedGrowth is like checkFixedSpace for closing an array or object,
+// which first removes shrink bytes and then adds extra bytes.
+func (b *Builder) checkFixedGrowth(extra, shrink uint) error {
+	if extra <= shrink {
+		return nil
+	}
+	return WithStack(b.checkFixedSpace(ValueLength(extra - shrink)))
+}
+
+// checkFixedBuffer returns an error if the generated bytes do not fit in the buffer given to NewBuilderWithBuffer.
+// All writes are checked beforehand by checkFixedSpace, so this only guards against
+// a write that would have grown the builder buffer beyond the given buffer.
+func (b *Builder) checkFixedBuffer() error {
+	if b.fixed == nil {
+		return nil
+	}
+	if b.buf.Len() > ValueLength(cap(b.fixed)) || cap(b.buf) != cap(b.fixed) {
+		return WithStack(BuilderBufferFullError)
+	}
 	return nil
 }
 
@@ -678,6 +990,7 @@ func (b *Builder) addCompoundValue(vType byte) {
 		toAdd--
 	}
 	b.index[stackLen-1].Clear()
+	b.invalidateKeySet(stackLen - 1)
 	dst := b.buf.Grow(9)
 	dst[0] = vType
 	//b.buf.WriteBytes(0, 8) // Will be filled later with bytelength and nr subs
@@ -698,7 +1011,7 @@ func (b *Builder) closeEmptyArrayOrObject(tos ValueLength, isArray bool) {
 
 // closeCompactArrayOrObject tries to close an array/object using compact notation.
 // Returns true when a compact notation was possible, false otherwise.
-func (b *Builder) closeCompactArrayOrObject(tos ValueLength, isArray bool, index indexVector) bool {
+func (b *Builder) closeCompactArrayOrObject(tos ValueLength, isArray bool, index indexVector) (bool, error) {
 	// use compact notation
 	nrItems := len(index)
 	nrItemsLen := getVariableValueLength(ValueLength(nrItems))
@@ -716,6 +1029,9 @@ func (b *Builder) closeCompactArrayOrObject(tos ValueLength, isArray bool, index
 
 	if byteSizeLen < 9 {
 		// can only use compact notation if total byte length is at most 8 bytes long
+		if err := b.checkFixedGrowth(uint(nrItemsLen), uint(8-byteSizeLen)); err != nil {
+			return false, WithStack(err)
+		}
 		if isArray {
 			b.buf[tos] = 0x13
 		} else {
@@ -742,9 +1058,9 @@ func (b *Builder) closeCompactArrayOrObject(tos ValueLength, isArray bool, index
 		storeVariableValueLength(b.buf, tos+byteSize-1, ValueLength(len(index)), true)
 
 		b.stack.Pop()
-		return true
+		return true, nil
 	}
-	return false
+	return false, nil
 }
 
 // checkAttributeUniqueness checks the given slice for duplicate keys.
@@ -858,10 +1174,7 @@ func (b *Builder) sortObjectIndex(objBase []byte, offsets []ValueLength) error {
 	return nil
 }
 
-func (b *Builder) closeArray(tos ValueLength, index []ValueLength) {
-	// fix head byte in case a compact Array was originally requested:
-	b.buf[tos] = 0x06
-
+func (b *Builder) closeArray(tos ValueLength, index []ValueLength) error {
 	needIndexTable := true
 	needNrSubs := true
 	if len(index) == 1 {
@@ -914,6 +1227,29 @@ func (b *Builder) closeArray(tos ValueLength, index []ValueLength) {
 		offsetSize = 8
 	}
 
+	// Check that the table fits before changing anything.
+	// In the 1-byte case, the reserved bytes that are not needed are given back first.
+	extraSpace := uint(0)
+	if needIndexTable {
+		extraSpace = offsetSize * uint(len(index))
+	}
+	if offsetSize == 8 && needNrSubs {
+		extraSpace += 8
+	}
+	shrink := uint(0)
+	if offsetSize == 1 {
+		shrink = 7
+		if needIndexTable {
+			shrink = 6
+		}
+	}
+	if err := b.checkFixedGrowth(extraSpace, shrink); err != nil {
+		return WithStack(err)
+	}
+
+	// fix head byte in case a compact Array was originally requested:
+	b.buf[tos] = 0x06
+
 	// Maybe we need to move down data:
 	if offsetSize == 1 {
 		targetPos := ValueLength(3)
@@ -990,11 +1326,13 @@ func (b *Builder) closeArray(tos ValueLength, index []ValueLength) {
 	// off the _stack:
 	b.stack.Pop()
 	// Intentionally leave _index[depth] intact to avoid future allocs!
+	return nil
 }
 
 func (b *Builder) cleanupAdd() {
 	depth := b.stack.Len() - 1
 	b.index[depth].RemoveLast()
+	b.invalidateKeySet(depth)
 }
 
 func (b *Builder) reportAdd() {
@@ -1033,6 +1371,9 @@ func (b *Builder) addInternal(v Value) error {
 		}
 		return WithStack(err)
 	}
+	if b.keyWritten {
+		b.lastKeyAdded()
+	}
 	return nil
 }
 
@@ -1072,11 +1413,21 @@ func (b *Builder) addInternalKey(attrName string) (haveReported bool, err error)
 		}
 	}
 
-	if err := b.set(NewStringValue(attrName)); err != nil {
+	if err := b.checkFixedSpace(stringByteSize(attrName)); err != nil {
+		onError()
+		return haveReported, WithStack(err)
+	}
+	if err := b.checkKeyIsString(true); err != nil {
+		onError()
+		return haveReported, WithStack(err)
+	}
+	b.addString(attrName)
+	if err := b.checkMaxSize(); err != nil {
 		onError()
 		return haveReported, WithStack(err)
 	}
 	b.keyWritten = true
+	b.keyAdded(attrName)
 	return haveReported, nil
 }
 
@@ -1122,13 +1473,19 @@ func (b *Builder) set(item Value) error {
 		if err != nil {
 			return WithStack(err)
 		}
+		if err := b.checkFixedSpace(l); err != nil {
+			return WithStack(err)
+		}
 		b.buf.Write(s[:l])
-		return nil
+		return WithStack(b.checkMaxSize())
 	}
 
 	// This method builds a single further VPack item at the current
 	// append position. If this is an array or object, then an index
 	// table is created and a new ValueLength is pushed onto the stack.
+	if err := b.checkFixedSpace(valueByteSize(item)); err != nil {
+		return WithStack(err)
+	}
 	switch item.vt {
 	case None:
 		return WithStack(BuilderUnexpectedTypeError{"Cannot set a ValueType::None"})
@@ -1168,8 +1525,14 @@ func (b *Builder) set(item Value) error {
 	case String:
 		b.addString(item.stringValue())
 	case Array:
+		if err := b.checkMaxDepth(); err != nil {
+			return WithStack(err)
+		}
 		b.addArray(item.unindexed)
 	case Object:
+		if err := b.checkMaxDepth(); err != nil {
+			return WithStack(err)
+		}
 		b.addObject(item.unindexed)
 	case Binary:
 		b.addBinary(item.binaryValue())
@@ -1184,5 +1547,5 @@ func (b *Builder) set(item Value) error {
 	case Custom:
 		return WithStack(fmt.Errorf("Cannot set a ValueType::Custom with this method"))
 	}
-	return nil
+	return WithStack(b.checkMaxSize())
 }
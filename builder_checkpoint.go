@@ -0,0 +1,76 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+// BuilderCheckpoint is a saved state of a Builder, created by Builder.Checkpoint.
+type BuilderCheckpoint struct {
+	generation uint64
+	size       ValueLength
+	depth      int
+	tos        ValueLength
+	indexLen   int
+	keyWritten bool
+}
+
+// Checkpoint returns the current state of the builder, so that all values
+// added after this call can be undone with Rollback.
+func (b *Builder) Checkpoint() BuilderCheckpoint {
+	tos, depth := b.stack.Tos()
+	cp := BuilderCheckpoint{
+		generation: b.generation,
+		size:       b.buf.Len(),
+		depth:      depth,
+		tos:        tos,
+		keyWritten: b.keyWritten,
+	}
+	if depth > 0 {
+		cp.indexLen = len(b.index[depth-1])
+	}
+	return cp
+}
+
+// Rollback restores the builder to the state of the given checkpoint.
+// All values added after the checkpoint was created are removed,
+// including arrays and objects that were opened after it, closed or not.
+// The checkpoint remains valid, so Rollback can be called again with it.
+// A checkpoint becomes invalid when the array or object that was open
+// when it was created is closed, when values added before it are removed,
+// when the builder is cleared or reset, or when a key is removed or replaced
+// with RemoveKey or ReplaceKey.
+// Rollback returns a BuilderInvalidCheckpointError if it detects an invalid checkpoint.
+func (b *Builder) Rollback(cp BuilderCheckpoint) error {
+	if cp.generation != b.generation || b.buf.Len() < cp.size || b.stack.Len() < cp.depth {
+		return WithStack(BuilderInvalidCheckpointError)
+	}
+	if cp.depth > 0 {
+		if b.stack.stack[cp.depth-1] != cp.tos || len(b.index[cp.depth-1]) < cp.indexLen {
+			return WithStack(BuilderInvalidCheckpointError)
+		}
+		b.index[cp.depth-1] = b.index[cp.depth-1][:cp.indexLen]
+		b.invalidateKeySet(cp.depth - 1)
+	}
+	b.stack.stack = b.stack.stack[:cp.depth]
+	b.buf = b.buf[:cp.size]
+	b.keyWritten = cp.keyWritten
+	return nil
+}
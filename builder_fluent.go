@@ -0,0 +1,248 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+import "time"
+
+// FluentBuilder wraps a Builder with chainable methods.
+// The first error that occurs is kept and all further calls are ignored,
+// so errors only have to be checked once, using Err.
+//
+//	f := b.Fluent()
+//	f.OpenObject().
+//		AddKeyString("name", "John").
+//		AddKeyInt("age", 42).
+//		Close()
+//	if err := f.Err(); err != nil { ... }
+type FluentBuilder struct {
+	b   *Builder
+	err error
+}
+
+// Fluent returns a FluentBuilder that adds to the given builder.
+func (b *Builder) Fluent() *FluentBuilder {
+	return &FluentBuilder{b: b}
+}
+
+// Builder returns the underlying builder.
+func (f *FluentBuilder) Builder() *Builder {
+	return f.b
+}
+
+// Err returns the first error that occurred, or nil.
+func (f *FluentBuilder) Err() error {
+	return f.err
+}
+
+// Slice returns a slice of the result, or the first error that occurred.
+func (f *FluentBuilder) Slice() (Slice, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	s, err := f.b.Slice()
+	return s, WithStack(err)
+}
+
+// OpenObject starts a new object.
+func (f *FluentBuilder) OpenObject(unindexed ...bool) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.OpenObject(unindexed...)
+	}
+	return f
+}
+
+// OpenArray starts a new array.
+func (f *FluentBuilder) OpenArray(unindexed ...bool) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.OpenArray(unindexed...)
+	}
+	return f
+}
+
+// OpenObjectKey adds a key to an open object and starts a new object as its value.
+func (f *FluentBuilder) OpenObjectKey(key string, unindexed ...bool) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.OpenObjectKey(key, unindexed...)
+	}
+	return f
+}
+
+// OpenArrayKey adds a key to an open object and starts a new array as its value.
+func (f *FluentBuilder) OpenArrayKey(key string, unindexed ...bool) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.OpenArrayKey(key, unindexed...)
+	}
+	return f
+}
+
+// Close ends an open object or array.
+func (f *FluentBuilder) Close() *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.Close()
+	}
+	return f
+}
+
+// AddValue adds a value to an array, object or as a raw value.
+func (f *FluentBuilder) AddValue(v Value) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddValue(v)
+	}
+	return f
+}
+
+// AddNull adds a null value.
+func (f *FluentBuilder) AddNull() *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddNull()
+	}
+	return f
+}
+
+// AddBool adds a bool value.
+func (f *FluentBuilder) AddBool(v bool) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddBool(v)
+	}
+	return f
+}
+
+// AddInt adds a signed integer value.
+func (f *FluentBuilder) AddInt(v int64) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddInt(v)
+	}
+	return f
+}
+
+// AddUInt adds an unsigned integer value.
+func (f *FluentBuilder) AddUInt(v uint64) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddUInt(v)
+	}
+	return f
+}
+
+// AddDouble adds a double value.
+func (f *FluentBuilder) AddDouble(v float64) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddDouble(v)
+	}
+	return f
+}
+
+// AddString adds a string value.
+func (f *FluentBuilder) AddString(v string) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddString(v)
+	}
+	return f
+}
+
+// AddBinary adds a binary value.
+func (f *FluentBuilder) AddBinary(v []byte) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddBinary(v)
+	}
+	return f
+}
+
+// AddUTCDate adds an UTC date value.
+func (f *FluentBuilder) AddUTCDate(v time.Time) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddUTCDate(v)
+	}
+	return f
+}
+
+// AddKeyValue adds a key with a value to an object.
+func (f *FluentBuilder) AddKeyValue(key string, v Value) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddKeyValue(key, v)
+	}
+	return f
+}
+
+// AddKeyNull adds a key with a null value to an object.
+func (f *FluentBuilder) AddKeyNull(key string) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddKeyNull(key)
+	}
+	return f
+}
+
+// AddKeyBool adds a key with a bool value to an object.
+func (f *FluentBuilder) AddKeyBool(key string, v bool) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddKeyBool(key, v)
+	}
+	return f
+}
+
+// AddKeyInt adds a key with a signed integer value to an object.
+func (f *FluentBuilder) AddKeyInt(key string, v int64) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddKeyInt(key, v)
+	}
+	return f
+}
+
+// AddKeyUInt adds a key with an unsigned integer value to an object.
+func (f *FluentBuilder) AddKeyUInt(key string, v uint64) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddKeyUInt(key, v)
+	}
+	return f
+}
+
+// AddKeyDouble adds a key with a double value to an object.
+func (f *FluentBuilder) AddKeyDouble(key string, v float64) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddKeyDouble(key, v)
+	}
+	return f
+}
+
+// AddKeyString adds a key with a string value to an object.
+func (f *FluentBuilder) AddKeyString(key, v string) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddKeyString(key, v)
+	}
+	return f
+}
+
+// AddKeyBinary adds a key with a binary value to an object.
+func (f *FluentBuilder) AddKeyBinary(key string, v []byte) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddKeyBinary(key, v)
+	}
+	return f
+}
+
+// AddKeyUTCDate adds a key with an UTC date value to an object.
+func (f *FluentBuilder) AddKeyUTCDate(key string, v time.Time) *FluentBuilder {
+	if f.err == nil {
+		f.err = f.b.AddKeyUTCDate(key, v)
+	}
+	return f
+}
@@ -0,0 +1,123 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+// keySet is the set of keys of an open object.
+// It is used by the builder when BuilderOptions.IndexKeys is set.
+// The set is built from the index vector when a key is first looked up,
+// and is kept up to date as keys are added until entries are removed.
+type keySet struct {
+	keys  map[string]int // Maps each key to the position of its first occurrence in the index vector
+	valid bool           // If false, the set must be rebuilt from the index vector before use
+}
+
+// Reset removes all keys from the set, prepares it for holding n keys and marks it valid.
+func (ks *keySet) Reset(n int) {
+	if ks.keys == nil || len(ks.keys) < n {
+		ks.keys = make(map[string]int, n)
+	} else {
+		for k := range ks.keys {
+			delete(ks.keys, k)
+		}
+	}
+	ks.valid = true
+}
+
+// Add records the given key found at the given position in the index vector.
+func (ks *keySet) Add(key string, pos int) {
+	if _, found := ks.keys[key]; !found {
+		ks.keys[key] = pos
+	}
+}
+
+// Invalidate marks the set as out of date.
+func (ks *keySet) Invalidate() {
+	ks.valid = false
+}
+
+// invalidateKeySet marks the key set of the open array or object at the given depth as out of date,
+// because it has just been opened or entries have been removed from its index vector.
+func (b *Builder) invalidateKeySet(depth int) {
+	if depth < len(b.keySets) {
+		b.keySets[depth].Invalidate()
+	}
+}
+
+// keyAdded records the given key, that has just been written to the open object at the top of the stack.
+func (b *Builder) keyAdded(key string) {
+	if !b.IndexKeys {
+		return
+	}
+	depth := b.stack.Len() - 1
+	if !b.keySetValid(depth) {
+		// Will be built on demand
+		return
+	}
+	b.keySets[depth].Add(key, len(b.index[depth])-1)
+}
+
+// keySetValid returns true if the key set of the open object at the given depth is up to date.
+func (b *Builder) keySetValid(depth int) bool {
+	return depth >= 0 && depth < len(b.keySets) && b.keySets[depth].valid
+}
+
+// lastKeyAdded records the key that has just been written to the open object at the top of the stack.
+func (b *Builder) lastKeyAdded() {
+	if !b.IndexKeys {
+		return
+	}
+	tos, stackLen := b.stack.Tos()
+	if !b.keySetValid(stackLen - 1) {
+		// Will be built on demand
+		return
+	}
+	index := b.index[stackLen-1]
+	key, err := Slice(b.buf[tos+index[len(index)-1]:]).GetString()
+	if err != nil {
+		b.invalidateKeySet(stackLen - 1)
+		return
+	}
+	b.keyAdded(key)
+}
+
+// keySet returns the up to date key set of the open object at the given depth.
+func (b *Builder) keySet(depth int) (*keySet, error) {
+	for len(b.keySets) <= depth {
+		b.keySets = append(b.keySets, keySet{})
+	}
+	ks := &b.keySets[depth]
+	if ks.valid {
+		return ks, nil
+	}
+	ks.Reset(len(b.index[depth]))
+	tos := b.stack.stack[depth]
+	for pos, idx := range b.index[depth] {
+		key, err := Slice(b.buf[tos+idx:]).GetString()
+		if err != nil {
+			ks.Invalidate()
+			return nil, WithStack(err)
+		}
+		ks.Add(key, pos)
+	}
+	return ks, nil
+}
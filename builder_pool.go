@@ -0,0 +1,63 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+import "sync"
+
+// defaultBuilderPool is the pool of builders used by Marshal.
+var defaultBuilderPool = BuilderPool{MaxCapacity: 1024 * 1024}
+
+// BuilderPool is a pool of Builders that can be reused to avoid
+// allocating a new buffer for every slice that is built.
+// The zero value is ready to use.
+// A BuilderPool is safe for concurrent use by multiple goroutines.
+type BuilderPool struct {
+	// MaxCapacity is the maximum capacity of the buffer of a builder returned to the pool.
+	// Builders with a larger buffer are dropped, so a single large slice does not
+	// keep its memory alive.
+	// A maximum capacity of 0 means no limit.
+	MaxCapacity int
+
+	pool sync.Pool
+}
+
+// Get returns an empty builder from the pool, allocating a new one if the pool is empty.
+func (p *BuilderPool) Get() *Builder {
+	if b, ok := p.pool.Get().(*Builder); ok {
+		return b
+	}
+	return &Builder{}
+}
+
+// Put resets the given builder and returns it to the pool.
+// The builder, and any slice obtained from it, must not be used after calling Put.
+// Builders created with NewBuilderWithBuffer are not returned to the pool,
+// since their buffer belongs to the caller.
+func (p *BuilderPool) Put(b *Builder) {
+	if b == nil || b.fixed != nil || (p.MaxCapacity > 0 && cap(b.buf) > p.MaxCapacity) {
+		return
+	}
+	b.Reset()
+	b.BuilderOptions = BuilderOptions{}
+	p.pool.Put(b)
+}
@@ -0,0 +1,203 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+// Projection selects the attributes of an object that are copied by Builder.AddProjection and Project.
+// Attributes are selected by paths, where a path is a list of keys, each selecting an attribute
+// of the object found by the keys before it (like the attribute path of Slice.Get).
+type Projection struct {
+	// Include contains the paths of the attributes to copy.
+	// If Include is empty, all attributes are copied, except those selected by Exclude.
+	Include [][]string
+	// Exclude contains the paths of the attributes that are not copied.
+	// Exclude takes precedence over Include.
+	Exclude [][]string
+	// Rename maps the keys of copied top-level attributes to the keys used in the result.
+	// Attributes whose key is not in Rename keep their key.
+	Rename map[string]string
+}
+
+// projectionNode is a node in the tree of paths of a Projection.
+type projectionNode struct {
+	children map[string]*projectionNode
+	all      bool // If set, a path ends at this node, so the entire attribute is selected
+}
+
+// newProjectionTree creates a tree from the given paths.
+// Returns nil if there are no paths.
+func newProjectionTree(paths [][]string) *projectionNode {
+	if len(paths) == 0 {
+		return nil
+	}
+	root := &projectionNode{}
+	for _, path := range paths {
+		n := root
+		for _, key := range path {
+			if n.all {
+				// A shorter path already selects the entire attribute
+				break
+			}
+			child, found := n.children[key]
+			if !found {
+				if n.children == nil {
+					n.children = make(map[string]*projectionNode)
+				}
+				child = &projectionNode{}
+				n.children[key] = child
+			}
+			n = child
+		}
+		n.all = true
+		n.children = nil
+	}
+	return root
+}
+
+// AddProjection adds the attributes of the given object that are selected by the given projection
+// to the open object at the top of the stack.
+// Attributes selected by a nested path are added as objects containing only the selected attributes.
+// Nested paths that lead to a value that is not an object select nothing from it.
+// Returns a DuplicateAttributeNameError when an attribute is added with a key that the object
+// already contains. For large objects, set BuilderOptions.IndexKeys to make this check fast.
+// When an error is returned, the builder is left as it was before the call.
+func (b *Builder) AddProjection(s Slice, p Projection) error {
+	if err := s.AssertType(Object); err != nil {
+		return WithStack(err)
+	}
+	if !b.IsOpenObject() {
+		return WithStack(BuilderNeedOpenObjectError)
+	}
+	if b.keyWritten {
+		return WithStack(BuilderKeyAlreadyWrittenError)
+	}
+	cp := b.Checkpoint()
+	if err := b.addProjection(s, newProjectionTree(p.Include), newProjectionTree(p.Exclude), p.Rename); err != nil {
+		b.Rollback(cp)
+		return WithStack(err)
+	}
+	return nil
+}
+
+// addProjection adds the attributes of the given object that are selected by the given include and exclude trees
+// to the open object at the top of the stack.
+// A nil include tree selects all attributes, a nil exclude tree excludes nothing.
+func (b *Builder) addProjection(s Slice, include, exclude *projectionNode, rename map[string]string) error {
+	if include != nil && include.all {
+		include = nil
+	}
+	if exclude != nil && exclude.all {
+		return nil
+	}
+	it, err := NewObjectIterator(s, true)
+	if err != nil {
+		return WithStack(err)
+	}
+	for it.IsValid() {
+		keySlice, err := it.Key(true)
+		if err != nil {
+			return WithStack(err)
+		}
+		key, err := keySlice.GetString()
+		if err != nil {
+			return WithStack(err)
+		}
+		value, err := it.Value()
+		if err != nil {
+			return WithStack(err)
+		}
+		if err := b.addProjectedAttribute(key, value, include, exclude, rename); err != nil {
+			return WithStack(err)
+		}
+		if err := it.Next(); err != nil {
+			return WithStack(err)
+		}
+	}
+	return nil
+}
+
+// addProjectedAttribute adds the given attribute to the open object at the top of the stack,
+// if it is selected by the given include and exclude trees.
+func (b *Builder) addProjectedAttribute(key string, value Slice, include, exclude *projectionNode, rename map[string]string) error {
+	// Find the selection of this attribute
+	var includeChild, excludeChild *projectionNode
+	if include != nil {
+		if includeChild = include.children[key]; includeChild == nil {
+			return nil
+		} else if includeChild.all {
+			includeChild = nil
+		}
+	}
+	if exclude != nil {
+		if excludeChild = exclude.children[key]; excludeChild != nil && excludeChild.all {
+			return nil
+		}
+	}
+	if includeChild != nil && !value.IsObject() {
+		// A nested path selects nothing from a value that is not an object
+		return nil
+	}
+
+	if newKey, found := rename[key]; found {
+		key = newKey
+	}
+	if pos, err := b.findKey(key); err != nil {
+		return WithStack(err)
+	} else if pos >= 0 {
+		return WithStack(DuplicateAttributeNameError)
+	}
+
+	if (includeChild == nil && excludeChild == nil) || !value.IsObject() {
+		// Copy the entire attribute
+		return WithStack(b.addInternalKeyValue(key, NewSliceValue(value)))
+	}
+	// Copy the selected attributes of the nested object
+	if err := b.OpenObjectKey(key); err != nil {
+		return WithStack(err)
+	}
+	if err := b.addProjection(value, includeChild, excludeChild, nil); err != nil {
+		return WithStack(err)
+	}
+	return WithStack(b.Close())
+}
+
+// Project creates an object that contains the attributes of the given object
+// that are selected by the given projection.
+// See Builder.AddProjection for details.
+func Project(s Slice, p Projection) (Slice, error) {
+	// Index the keys, so checking for duplicates does not scan the result for every attribute.
+	b := Builder{BuilderOptions: BuilderOptions{IndexKeys: true}}
+	if err := b.OpenObject(); err != nil {
+		return nil, WithStack(err)
+	}
+	if err := b.AddProjection(s, p); err != nil {
+		return nil, WithStack(err)
+	}
+	if err := b.Close(); err != nil {
+		return nil, WithStack(err)
+	}
+	result, err := b.Slice()
+	if err != nil {
+		return nil, WithStack(err)
+	}
+	return result, nil
+}
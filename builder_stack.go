@@ -50,6 +50,11 @@ func (s *builderStack) Clear() {
 	s.stack = nil
 }
 
+// Reset removes all values from the stack, keeping its capacity.
+func (s *builderStack) Reset() {
+	s.stack = s.stack[:0]
+}
+
 // Tos returns the value at the top of the stack.
 // Returns <value at top of stack>, <stack length>
 func (s builderStack) Tos() (ValueLength, int) {
@@ -0,0 +1,262 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+import "time"
+
+// AddNull adds a null value to an array, object or as a raw value.
+func (b *Builder) AddNull() error {
+	haveReported, err := b.beginValue(false, 1)
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addNull()
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddBool adds a bool value to an array, object or as a raw value.
+func (b *Builder) AddBool(v bool) error {
+	haveReported, err := b.beginValue(false, 1)
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addBool(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddInt adds a signed integer value to an array, object or as a raw value.
+func (b *Builder) AddInt(v int64) error {
+	haveReported, err := b.beginValue(false, intByteSize(v))
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addInt(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddUInt adds an unsigned integer value to an array, object or as a raw value.
+func (b *Builder) AddUInt(v uint64) error {
+	haveReported, err := b.beginValue(false, uintByteSize(v))
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addUInt(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddDouble adds a double value to an array, object or as a raw value.
+func (b *Builder) AddDouble(v float64) error {
+	haveReported, err := b.beginValue(false, 9)
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addDouble(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddString adds a string value to an array, object or as a raw value.
+// When an object is open and no key has been written, the string is used as key.
+func (b *Builder) AddString(v string) error {
+	haveReported, err := b.beginValue(true, stringByteSize(v))
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addString(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddBinary adds a binary value to an array, object or as a raw value.
+func (b *Builder) AddBinary(v []byte) error {
+	haveReported, err := b.beginValue(false, binaryByteSize(v))
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addBinary(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddUTCDate adds an UTC date value to an array, object or as a raw value.
+func (b *Builder) AddUTCDate(v time.Time) error {
+	haveReported, err := b.beginValue(false, 9)
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addUTCDate(utcDateMillis(v))
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddKeyNull adds a key with a null value to an object.
+func (b *Builder) AddKeyNull(key string) error {
+	haveReported, err := b.beginKeyValue(key, 1)
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addNull()
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddKeyBool adds a key with a bool value to an object.
+func (b *Builder) AddKeyBool(key string, v bool) error {
+	haveReported, err := b.beginKeyValue(key, 1)
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addBool(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddKeyInt adds a key with a signed integer value to an object.
+func (b *Builder) AddKeyInt(key string, v int64) error {
+	haveReported, err := b.beginKeyValue(key, intByteSize(v))
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addInt(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddKeyUInt adds a key with an unsigned integer value to an object.
+func (b *Builder) AddKeyUInt(key string, v uint64) error {
+	haveReported, err := b.beginKeyValue(key, uintByteSize(v))
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addUInt(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddKeyDouble adds a key with a double value to an object.
+func (b *Builder) AddKeyDouble(key string, v float64) error {
+	haveReported, err := b.beginKeyValue(key, 9)
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addDouble(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddKeyString adds a key with a string value to an object.
+func (b *Builder) AddKeyString(key, v string) error {
+	haveReported, err := b.beginKeyValue(key, stringByteSize(v))
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addString(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddKeyBinary adds a key with a binary value to an object.
+func (b *Builder) AddKeyBinary(key string, v []byte) error {
+	haveReported, err := b.beginKeyValue(key, binaryByteSize(v))
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addBinary(v)
+	return WithStack(b.endValue(haveReported))
+}
+
+// AddKeyUTCDate adds a key with an UTC date value to an object.
+func (b *Builder) AddKeyUTCDate(key string, v time.Time) error {
+	haveReported, err := b.beginKeyValue(key, 9)
+	if err != nil {
+		return WithStack(err)
+	}
+	b.addUTCDate(utcDateMillis(v))
+	return WithStack(b.endValue(haveReported))
+}
+
+// OpenObjectKey adds a key to an open object and starts a new object as its value.
+// This must be closed using Close.
+func (b *Builder) OpenObjectKey(key string, unindexed ...bool) error {
+	if !b.IsOpenObject() {
+		return WithStack(BuilderNeedOpenObjectError)
+	}
+	if _, err := b.addInternalKey(key); err != nil {
+		return WithStack(err)
+	}
+	return WithStack(b.OpenObject(unindexed...))
+}
+
+// OpenArrayKey adds a key to an open object and starts a new array as its value.
+// This must be closed using Close.
+func (b *Builder) OpenArrayKey(key string, unindexed ...bool) error {
+	if !b.IsOpenObject() {
+		return WithStack(BuilderNeedOpenObjectError)
+	}
+	if _, err := b.addInternalKey(key); err != nil {
+		return WithStack(err)
+	}
+	return WithStack(b.OpenArray(unindexed...))
+}
+
+// beginValue prepares adding a value of the given byte size to an open array or object, or as a raw value.
+// Returns true if the value has been reported to the open array or object.
+func (b *Builder) beginValue(isString bool, size ValueLength) (bool, error) {
+	if err := b.checkFixedSpace(size); err != nil {
+		return false, WithStack(err)
+	}
+	haveReported := false
+	if !b.stack.IsEmpty() && !b.keyWritten {
+		b.reportAdd()
+		haveReported = true
+	}
+	if err := b.checkKeyIsString(isString); err != nil {
+		if haveReported {
+			b.cleanupAdd()
+		}
+		return false, WithStack(err)
+	}
+	return haveReported, nil
+}
+
+// beginKeyValue adds the given key to an open object and prepares adding its value of the given byte size.
+// Returns true if the key has been reported to the open object.
+func (b *Builder) beginKeyValue(key string, size ValueLength) (bool, error) {
+	if !b.IsOpenObject() {
+		return false, WithStack(BuilderNeedOpenObjectError)
+	}
+	if err := b.checkFixedSpace(stringByteSize(key) + size); err != nil {
+		return false, WithStack(err)
+	}
+	haveReported, err := b.addInternalKey(key)
+	if err != nil {
+		return false, WithStack(err)
+	}
+	// The value follows the key, so reset keyWritten.
+	b.keyWritten = false
+	return haveReported, nil
+}
+
+// endValue finishes adding a value started with beginValue or beginKeyValue.
+func (b *Builder) endValue(haveReported bool) error {
+	if err := b.checkMaxSize(); err != nil {
+		if haveReported {
+			b.cleanupAdd()
+		}
+		return WithStack(err)
+	}
+	if b.keyWritten {
+		b.lastKeyAdded()
+	}
+	return nil
+}
@@ -26,6 +26,7 @@
 package velocypack
 
 import (
+	"bufio"
 	"bytes"
 	"encoding"
 	"encoding/base64"
@@ -39,7 +40,30 @@ import (
 
 // A Decoder decodes velocypack values into Go structures.
 type Decoder struct {
-	r io.Reader
+	r       *bufio.Reader
+	maxSize ValueLength
+	options DecoderOptions
+}
+
+// DecoderOptions contains options that control the decoding of velocypack values.
+type DecoderOptions struct {
+	// AttributeTranslator is used to translate integer object keys into strings.
+	// If nil, the default translator is used, which knows the ArangoDB
+	// system attributes (_key, _rev, _id, _from, _to).
+	AttributeTranslator AttributeTranslator
+	// If set, decoding an integer object key that is unknown to the attribute translator
+	// results in an UnknownAttributeIDError.
+	// Otherwise such keys are decoded as their decimal representation.
+	DisallowUnknownAttributeIDs bool
+	// TypeDecoders contains functions used to decode values of specific types,
+	// taking precedence over all other decodings of these types.
+	// Each function is called with the slice to decode and a settable value
+	// of the type it is registered for.
+	TypeDecoders map[reflect.Type]func(Slice, reflect.Value) error
+	// If set, decoding continues after a value that does not fit its Go type
+	// and all such mismatches are returned as UnmarshalTypeErrors.
+	// Otherwise only the first mismatch is returned as UnmarshalTypeError.
+	AllErrors bool
 }
 
 // Unmarshaler is implemented by types that can convert themselves from Velocypack.
@@ -47,11 +71,43 @@ type Unmarshaler interface {
 	UnmarshalVPack(Slice) error
 }
 
+// IteratorUnmarshaler is implemented by types that can read themselves
+// from the attributes of a Velocypack object.
+// The iterator reads directly from the data being unmarshaled, so keys and
+// values must be copied if they are retained after UnmarshalVPackFrom returns.
+type IteratorUnmarshaler interface {
+	UnmarshalVPackFrom(it *ObjectIterator) error
+}
+
+// iteratorUnmarshaler adapts an IteratorUnmarshaler to the Unmarshaler interface.
+type iteratorUnmarshaler struct {
+	u IteratorUnmarshaler
+}
+
+// UnmarshalVPack implements Unmarshaler for iteratorUnmarshaler.
+func (a iteratorUnmarshaler) UnmarshalVPack(s Slice) error {
+	it, err := NewObjectIterator(s)
+	if err != nil {
+		return WithStack(err)
+	}
+	if err := a.u.UnmarshalVPackFrom(it); err != nil {
+		return WithStack(err)
+	}
+	return nil
+}
+
 // NewDecoder creates a new Decoder that reads data from the given reader.
-func NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{
-		r: r,
+//
+// The decoder introduces its own buffering and may
+// read data from r beyond the velocypack values requested.
+func NewDecoder(r io.Reader, options ...DecoderOptions) *Decoder {
+	d := &Decoder{
+		r: bufio.NewReader(r),
 	}
+	if len(options) > 0 {
+		d.options = options[0]
+	}
+	return d
 }
 
 // Unmarshal reads v from the given Velocypack encoded data slice.
@@ -69,14 +125,22 @@ func NewDecoder(r io.Reader) *Decoder {
 // To unmarshal VelocyPack into a value implementing the Unmarshaler interface,
 // Unmarshal calls that value's UnmarshalVPack method, including
 // when the input is a VelocyPack Null.
+// To unmarshal a VelocyPack object into a value implementing the IteratorUnmarshaler
+// interface, Unmarshal calls that value's UnmarshalVPackFrom method with an
+// iterator over the attributes of the object.
 // Otherwise, if the value implements encoding.TextUnmarshaler
 // and the input is a VelocyPack quoted string, Unmarshal calls that value's
 // UnmarshalText method with the unquoted form of the string.
+// If the value implements encoding.BinaryUnmarshaler and the input is
+// VelocyPack Binary data, Unmarshal calls that value's UnmarshalBinary method
+// with the binary data.
 //
 // To unmarshal VelocyPack into a struct, Unmarshal matches incoming object
 // keys to the keys used by Marshal (either the struct field name or its tag),
 // preferring an exact match but also accepting a case-insensitive match.
 // Unmarshal will only set exported fields of the struct.
+// Object keys that do not match any field are stored in the map field
+// tagged ",rest" or ",inline" (if any), such as a map[string]RawSlice.
 //
 // To unmarshal VelocyPack into an interface value,
 // Unmarshal stores one of these in the interface value:
@@ -90,6 +154,8 @@ func NewDecoder(r io.Reader) *Decoder {
 //	map[string]interface{}, for VelocyPack Object's
 //	nil for VelocyPack Null.
 //	[]byte for VelocyPack Binary.
+//	MinKeySentinel, MaxKeySentinel, IllegalSentinel and NoneSentinel
+//	for VelocyPack MinKey, MaxKey, Illegal and None.
 //
 // To unmarshal a VelocyPack array into a slice, Unmarshal resets the slice length
 // to zero and then appends each element to the slice.
@@ -113,15 +179,44 @@ func NewDecoder(r io.Reader) *Decoder {
 // or if a VelocyPack number overflows the target type, Unmarshal
 // skips that field and completes the unmarshaling as best it can.
 // If no more serious errors are encountered, Unmarshal returns
-// an UnmarshalTypeError describing the earliest such error.
+// an UnmarshalTypeError describing the earliest such error,
+// including the path of the value in the document and its byte offset.
+// If the AllErrors option is set, Unmarshal returns an UnmarshalTypeErrors
+// describing all such errors instead.
 //
 // The VelocyPack Null value unmarshals into an interface, map, pointer, or slice
 // by setting that Go value to nil. Because null is often used in VelocyPack to mean
 // ``not present,'' unmarshaling a VelocyPack Null into any other Go type has no effect
 // on the value and produces no error.
 //
-func Unmarshal(data Slice, v interface{}) error {
-	if err := unmarshalSlice(data, v); err != nil {
+// Integer object keys are translated into strings using the attribute translator
+// of the given options, except when unmarshaling into a map with integer keys.
+// In that case the integer keys are used as is.
+//
+func Unmarshal(data Slice, v interface{}, options ...DecoderOptions) error {
+	d := &decodeState{}
+	if len(options) > 0 {
+		d.options = options[0]
+	}
+	if err := unmarshalSlice(data, v, d); err != nil {
+		return WithStack(err)
+	}
+	return nil
+}
+
+// UnmarshalFields reads only the given attribute paths from the given
+// Velocypack encoded data slice into v.
+// An attribute path is a list of attribute names separated by '.',
+// such as "address.city". Paths apply to the elements of arrays as well.
+// Attributes that are not included in any of the given paths are
+// skipped without being decoded, leaving the corresponding fields of v untouched.
+// If no paths are given, UnmarshalFields behaves like Unmarshal.
+func UnmarshalFields(data Slice, v interface{}, paths ...string) error {
+	d := &decodeState{}
+	if len(paths) > 0 {
+		d.mask = newFieldMask(paths)
+	}
+	if err := unmarshalSlice(data, v, d); err != nil {
 		return WithStack(err)
 	}
 	return nil
@@ -129,18 +224,52 @@ func Unmarshal(data Slice, v interface{}) error {
 
 // Decode reads v from the decoder stream.
 func (e *Decoder) Decode(v interface{}) error {
-	s, err := SliceFromReader(e.r)
+	s, err := sliceFromBufReader(e.r, e.maxSize)
 	if err != nil {
 		return WithStack(err)
 	}
-	if err := unmarshalSlice(s, v); err != nil {
+	if err := unmarshalSlice(s, v, &decodeState{options: e.options}); err != nil {
 		return WithStack(err)
 	}
 	return nil
 }
 
-// unmarshalSlice reads v from the given slice.
-func unmarshalSlice(data Slice, v interface{}) (err error) {
+// More reports whether there is another slice in the decoder stream.
+func (e *Decoder) More() bool {
+	_, err := e.r.Peek(1)
+	return err == nil
+}
+
+// Buffered returns a reader of the data remaining in the Decoder's buffer.
+// The reader is valid until the next call to Decode or Token.
+func (e *Decoder) Buffered() io.Reader {
+	buf, _ := e.r.Peek(e.r.Buffered())
+	return bytes.NewReader(buf)
+}
+
+// Token returns the next slice in the decoder stream without decoding it.
+// At the end of the stream, Token returns nil, io.EOF.
+func (e *Decoder) Token() (Slice, error) {
+	s, err := sliceFromBufReader(e.r, e.maxSize)
+	if err != nil {
+		return nil, WithStack(err)
+	}
+	if s == nil {
+		return nil, io.EOF
+	}
+	return s, nil
+}
+
+// SetMaxSize limits the size of a single slice read by Decode and Token.
+// Slices with a larger length header are rejected with a SliceTooLargeError
+// before any memory is allocated for them.
+// A maximum size of 0 means no limit.
+func (e *Decoder) SetMaxSize(maxSize ValueLength) {
+	e.maxSize = maxSize
+}
+
+// unmarshalSlice reads v from the given slice, using the given decode state.
+func unmarshalSlice(data Slice, v interface{}, d *decodeState) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if _, ok := r.(runtime.Error); ok {
@@ -155,10 +284,13 @@ func unmarshalSlice(data Slice, v interface{}) (err error) {
 		return &InvalidUnmarshalError{reflect.TypeOf(v)}
 	}
 
-	d := &decodeState{}
 	// We decode rv not rv.Elem because the Unmarshaler interface
 	// test must be applied at the top level of the value.
+	d.root = data
 	d.unmarshalValue(data, rv)
+	if d.savedError == nil && len(d.typeErrors) > 0 {
+		return d.typeErrors
+	}
 	return d.savedError
 }
 
@@ -168,12 +300,25 @@ var (
 )
 
 type decodeState struct {
+	options      DecoderOptions
 	useNumber    bool
-	errorContext struct { // provides context for type errors
+	mask         fieldMask // if not nil, only attributes in this mask are decoded
+	errorContext struct {  // provides context for type errors
 		Struct string
 		Field  string
 	}
 	savedError error
+	typeErrors UnmarshalTypeErrors // type errors collected when options.AllErrors is set
+	root       Slice               // slice passed to unmarshalSlice
+	current    Slice               // value being unmarshaled
+	path       []pathElement       // path of the current value in root
+}
+
+// pathElement is a single step in the path of a value in a document.
+// It is an object key, or an array index if index >= 0.
+type pathElement struct {
+	key   []byte
+	index int
 }
 
 // error aborts the decoding by panicking with err.
@@ -183,39 +328,137 @@ func (d *decodeState) error(err error) {
 
 // saveError saves the first err it is called with,
 // for reporting at the end of the unmarshal.
+// If options.AllErrors is set, all UnmarshalTypeErrors are saved.
 func (d *decodeState) saveError(err error) {
+	if d.options.AllErrors {
+		if te, ok := err.(*UnmarshalTypeError); ok {
+			d.typeErrors = append(d.typeErrors, d.addErrorContext(te).(*UnmarshalTypeError))
+			return
+		}
+	}
 	if d.savedError == nil {
 		d.savedError = d.addErrorContext(err)
 	}
 }
 
 // addErrorContext returns a new error enhanced with information from d.errorContext
+// and the path and offset of the current value.
 func (d *decodeState) addErrorContext(err error) error {
-	if d.errorContext.Struct != "" || d.errorContext.Field != "" {
-		switch err := err.(type) {
-		case *UnmarshalTypeError:
+	switch err := err.(type) {
+	case *UnmarshalTypeError:
+		if d.errorContext.Struct != "" || d.errorContext.Field != "" {
 			err.Struct = d.errorContext.Struct
 			err.Field = d.errorContext.Field
-			return err
 		}
+		err.Path = d.currentPath()
+		err.Found = d.current.Type()
+		err.Offset = d.offsetOf(d.current)
+		return err
 	}
 	return err
 }
 
+// pushIndex adds an array index to the path of the current value.
+func (d *decodeState) pushIndex(index int) {
+	d.path = append(d.path, pathElement{index: index})
+}
+
+// pushKey adds an object key to the path of the current value.
+func (d *decodeState) pushKey(key []byte) {
+	d.path = append(d.path, pathElement{key: key, index: -1})
+}
+
+// popPath removes the last element from the path of the current value.
+func (d *decodeState) popPath() {
+	d.path = d.path[:len(d.path)-1]
+}
+
+// currentPath returns the path of the current value, such as "users[3].address.zip".
+func (d *decodeState) currentPath() string {
+	var buf bytes.Buffer
+	for _, e := range d.path {
+		if e.index >= 0 {
+			buf.WriteByte('[')
+			buf.WriteString(strconv.Itoa(e.index))
+			buf.WriteByte(']')
+		} else {
+			if buf.Len() > 0 {
+				buf.WriteByte('.')
+			}
+			buf.Write(e.key)
+		}
+	}
+	return buf.String()
+}
+
+// offsetOf returns the byte offset of s in the slice being unmarshaled,
+// or -1 if s is not part of it.
+func (d *decodeState) offsetOf(s Slice) int64 {
+	if len(s) == 0 || len(d.root) == 0 {
+		return -1
+	}
+	offset := cap(d.root) - cap(s)
+	if offset < 0 || offset >= len(d.root) || &d.root[offset] != &s[0] {
+		return -1
+	}
+	return int64(offset)
+}
+
 // unmarshalValue unmarshals any slice into given v.
 func (d *decodeState) unmarshalValue(data Slice, v reflect.Value) {
 	if !v.IsValid() {
 		return
 	}
+	parent := d.current
+	d.current = data
+	if len(d.options.TypeDecoders) > 0 && d.unmarshalHook(data, v) {
+		d.current = parent
+		return
+	}
 
 	switch data.Type() {
 	case Array:
 		d.unmarshalArray(data, v)
 	case Object:
 		d.unmarshalObject(data, v)
-	case Bool, Int, SmallInt, UInt, Double, Binary, BCD, String:
+	case Bool, Int, SmallInt, UInt, Double, Binary, BCD, String, MinKey, MaxKey, Illegal:
 		d.unmarshalLiteral(data, v)
+	case None:
+		if len(data) > 0 {
+			d.unmarshalLiteral(data, v)
+		}
+	}
+	d.current = parent
+}
+
+// unmarshalHook unmarshals data into v using a type decoder hook registered
+// for the type of v, or for a type v points to.
+// Returns false if there is no such hook.
+func (d *decodeState) unmarshalHook(data Slice, v reflect.Value) bool {
+	// Find a hook without allocating pointers first.
+	t := v.Type()
+	hook, found := d.options.TypeDecoders[t]
+	for !found && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		hook, found = d.options.TypeDecoders[t]
+	}
+	if !found {
+		return false
+	}
+	for v.Type() != t {
+		if data.IsNull() {
+			// Leave setting pointers to nil to the default decoding.
+			return false
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if err := hook(data, v); err != nil {
+		d.error(err)
 	}
+	return true
 }
 
 // indirect walks down v allocating pointers as needed,
@@ -254,6 +497,9 @@ func (d *decodeState) indirect(v reflect.Value, decodingNull bool) (Unmarshaler,
 			if u, ok := v.Interface().(Unmarshaler); ok {
 				return u, nil, nil, reflect.Value{}
 			}
+			if u, ok := v.Interface().(IteratorUnmarshaler); ok {
+				return iteratorUnmarshaler{u}, nil, nil, reflect.Value{}
+			}
 			if u, ok := v.Interface().(json.Unmarshaler); ok {
 				return nil, u, nil, reflect.Value{}
 			}
@@ -309,6 +555,9 @@ func (d *decodeState) unmarshalArray(data Slice, v reflect.Value) {
 	default:
 		d.saveError(&UnmarshalTypeError{Value: "array", Type: v.Type()})
 		return
+	case reflect.Map:
+		d.unmarshalMapPairs(data, v)
+		return
 	case reflect.Array:
 	case reflect.Slice:
 		break
@@ -344,7 +593,9 @@ func (d *decodeState) unmarshalArray(data Slice, v reflect.Value) {
 
 		if i < v.Len() {
 			// Decode into element.
+			d.pushIndex(i)
 			d.unmarshalValue(value, v.Index(i))
+			d.popPath()
 		}
 		i++
 		if err := it.Next(); err != nil {
@@ -368,6 +619,59 @@ func (d *decodeState) unmarshalArray(data Slice, v reflect.Value) {
 	}
 }
 
+// unmarshalMapPairs unmarshals an array of [key, value] pairs into map v.
+func (d *decodeState) unmarshalMapPairs(data Slice, v reflect.Value) {
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	i := 0
+	it, err := NewArrayIterator(data)
+	if err != nil {
+		d.error(err)
+	}
+	for it.IsValid() {
+		pair, err := it.Value()
+		if err != nil {
+			d.error(err)
+		}
+		if !pair.IsArray() {
+			d.saveError(&UnmarshalTypeError{Value: "array without [key, value] pairs", Type: t})
+			return
+		}
+		if l, err := pair.Length(); err != nil {
+			d.error(err)
+		} else if l != 2 {
+			d.saveError(&UnmarshalTypeError{Value: "array without [key, value] pairs", Type: t})
+			return
+		}
+		key, err := pair.At(0)
+		if err != nil {
+			d.error(err)
+		}
+		value, err := pair.At(1)
+		if err != nil {
+			d.error(err)
+		}
+		d.pushIndex(i)
+		kv := reflect.New(t.Key()).Elem()
+		d.pushIndex(0)
+		d.unmarshalValue(key, kv)
+		d.popPath()
+		ev := reflect.New(t.Elem()).Elem()
+		d.pushIndex(1)
+		d.unmarshalValue(value, ev)
+		d.popPath()
+		d.popPath()
+		v.SetMapIndex(kv, ev)
+		i++
+
+		if err := it.Next(); err != nil {
+			d.error(err)
+		}
+	}
+}
+
 // unmarshalObject unmarshals an object slice into given v.
 func (d *decodeState) unmarshalObject(data Slice, v reflect.Value) {
 	// Check for unmarshaler.
@@ -401,6 +705,14 @@ func (d *decodeState) unmarshalObject(data Slice, v reflect.Value) {
 		return
 	}
 
+	// Decoding into interface with registered concrete types?
+	if v.Kind() == reflect.Interface {
+		if pi := lookupPolymorphicInterface(v.Type()); pi != nil {
+			d.unmarshalPolymorphic(data, v, pi)
+			return
+		}
+	}
+
 	// Check type of target:
 	//   struct or
 	//   map[T1]T2 where T1 is string, an integer type,
@@ -431,24 +743,40 @@ func (d *decodeState) unmarshalObject(data Slice, v reflect.Value) {
 	}
 
 	var mapElem reflect.Value
+	// Integer keys are not translated when decoding into a map with integer keys.
+	translate := v.Kind() != reflect.Map || !isIntegerKind(v.Type().Key().Kind())
 
 	it, err := NewObjectIterator(data)
 	if err != nil {
 		d.error(err)
 	}
 	for it.IsValid() {
-		key, err := it.Key(true)
+		rawKey, err := it.Key(false)
 		if err != nil {
 			d.error(err)
 		}
+		key := d.objectKey(rawKey, translate)
 		keyUTF8, err := key.GetStringUTF8()
 		if err != nil {
 			d.error(err)
 		}
+		mask := d.mask
+		if mask != nil {
+			subMask, found := mask[string(keyUTF8)]
+			if !found {
+				// Attribute not selected, skip it.
+				if err := it.Next(); err != nil {
+					d.error(err)
+				}
+				continue
+			}
+			d.mask = subMask
+		}
 		value, err := it.Value()
 		if err != nil {
 			d.error(err)
 		}
+		d.pushKey(keyUTF8)
 
 		// Figure out field corresponding to key.
 		var subv reflect.Value
@@ -463,10 +791,14 @@ func (d *decodeState) unmarshalObject(data Slice, v reflect.Value) {
 			}
 			subv = mapElem
 		} else {
-			var f *field
+			var f, rest *field
 			fields := cachedTypeFields(v.Type())
 			for i := range fields {
 				ff := &fields[i]
+				if ff.rest {
+					rest = ff
+					continue
+				}
 				if bytes.Equal(ff.nameBytes, key) {
 					f = ff
 					break
@@ -489,6 +821,15 @@ func (d *decodeState) unmarshalObject(data Slice, v reflect.Value) {
 				}
 				d.errorContext.Field = f.name
 				d.errorContext.Struct = v.Type().Name()
+			} else if rest != nil {
+				// Collect unknown attribute in rest map.
+				d.unmarshalRest(keyUTF8, value, v, rest)
+				d.popPath()
+				d.mask = mask
+				if err := it.Next(); err != nil {
+					d.error(err)
+				}
+				continue
 			}
 		}
 
@@ -527,6 +868,7 @@ func (d *decodeState) unmarshalObject(data Slice, v reflect.Value) {
 					n, err := strconv.ParseInt(keyStr, 10, 64)
 					if err != nil || reflect.Zero(kt).OverflowInt(n) {
 						d.saveError(&UnmarshalTypeError{Value: "number " + keyStr, Type: kt})
+						d.popPath()
 						return
 					}
 					kv = reflect.ValueOf(n).Convert(kt)
@@ -534,6 +876,7 @@ func (d *decodeState) unmarshalObject(data Slice, v reflect.Value) {
 					n, err := strconv.ParseUint(keyStr, 10, 64)
 					if err != nil || reflect.Zero(kt).OverflowUint(n) {
 						d.saveError(&UnmarshalTypeError{Value: "number " + keyStr, Type: kt})
+						d.popPath()
 						return
 					}
 					kv = reflect.ValueOf(n).Convert(kt)
@@ -546,6 +889,8 @@ func (d *decodeState) unmarshalObject(data Slice, v reflect.Value) {
 
 		d.errorContext.Struct = ""
 		d.errorContext.Field = ""
+		d.popPath()
+		d.mask = mask
 
 		if err := it.Next(); err != nil {
 			d.error(err)
@@ -553,6 +898,116 @@ func (d *decodeState) unmarshalObject(data Slice, v reflect.Value) {
 	}
 }
 
+// objectKey returns the given object key as a String slice.
+// If translate is set, integer keys are translated using the attribute translator.
+// Otherwise integer keys are converted to their decimal representation.
+func (d *decodeState) objectKey(key Slice, translate bool) Slice {
+	switch {
+	case key.IsString():
+		return key
+	case translate && (key.IsSmallInt() || key.IsUInt()):
+		id, err := key.GetUInt()
+		if err != nil {
+			d.error(err)
+		}
+		translator := d.options.AttributeTranslator
+		if translator == nil {
+			translator = attributeTranslator
+		}
+		if translator == nil {
+			d.error(NeedAttributeTranslatorError)
+		}
+		if name := translator.IDToString(id); name != "" {
+			return StringSlice(name)
+		}
+		if d.options.DisallowUnknownAttributeIDs {
+			d.error(UnknownAttributeIDError)
+		}
+		return StringSlice(strconv.FormatUint(id, 10))
+	case key.IsUInt():
+		id, err := key.GetUInt()
+		if err != nil {
+			d.error(err)
+		}
+		return StringSlice(strconv.FormatUint(id, 10))
+	case key.IsInteger():
+		id, err := key.GetInt()
+		if err != nil {
+			d.error(err)
+		}
+		return StringSlice(strconv.FormatInt(id, 10))
+	default:
+		d.error(InvalidTypeError{"Cannot translate key of this type"})
+		return nil
+	}
+}
+
+// isIntegerKind returns true if the given kind is a signed or unsigned integer kind.
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// unmarshalPolymorphic unmarshals an object slice into interface v,
+// using the discriminator attribute of the object to select the concrete type.
+func (d *decodeState) unmarshalPolymorphic(data Slice, v reflect.Value, pi *polymorphicInterface) {
+	discriminator, err := data.Get(pi.attribute)
+	if err != nil {
+		d.error(err)
+	}
+	if len(discriminator) == 0 || !discriminator.IsString() {
+		d.saveError(&UnmarshalTypeError{Value: "object without " + pi.attribute + " attribute", Type: v.Type()})
+		return
+	}
+	name, err := discriminator.GetString()
+	if err != nil {
+		d.error(err)
+	}
+	t, found := pi.types[name]
+	if !found {
+		d.saveError(&UnmarshalTypeError{Value: "object of unknown " + pi.attribute + " " + strconv.Quote(name), Type: v.Type()})
+		return
+	}
+	var nv reflect.Value
+	if t.Kind() == reflect.Ptr {
+		nv = reflect.New(t.Elem())
+		d.unmarshalValue(data, nv)
+	} else {
+		nv = reflect.New(t)
+		d.unmarshalValue(data, nv)
+		nv = nv.Elem()
+	}
+	v.Set(nv)
+}
+
+// unmarshalRest stores the given attribute in the rest map field of struct v.
+func (d *decodeState) unmarshalRest(keyUTF8 []byte, value Slice, v reflect.Value, rest *field) {
+	mv := v
+	for _, i := range rest.index {
+		if mv.Kind() == reflect.Ptr {
+			if mv.IsNil() {
+				mv.Set(reflect.New(mv.Type().Elem()))
+			}
+			mv = mv.Elem()
+		}
+		mv = mv.Field(i)
+	}
+	if mv.IsNil() {
+		mv.Set(reflect.MakeMap(mv.Type()))
+	}
+	elem := reflect.New(mv.Type().Elem()).Elem()
+	d.errorContext.Field = rest.name
+	d.errorContext.Struct = v.Type().Name()
+	d.unmarshalValue(value, elem)
+	d.errorContext.Struct = ""
+	d.errorContext.Field = ""
+	mv.SetMapIndex(reflect.ValueOf(string(keyUTF8)).Convert(mv.Type().Key()), elem)
+}
+
 // unmarshalLiteral unmarshals a literal slice into given v.
 func (d *decodeState) unmarshalLiteral(data Slice, v reflect.Value) {
 	d.literalStore(data, v, false)
@@ -609,14 +1064,27 @@ func (d *decodeState) objectInterface(data Slice) map[string]interface{} {
 		d.error(err)
 	}
 	for it.IsValid() {
-		key, err := it.Key(true)
+		rawKey, err := it.Key(false)
 		if err != nil {
 			d.error(err)
 		}
+		key := d.objectKey(rawKey, true)
 		keyStr, err := key.GetString()
 		if err != nil {
 			d.error(err)
 		}
+		mask := d.mask
+		if mask != nil {
+			subMask, found := mask[keyStr]
+			if !found {
+				// Attribute not selected, skip it.
+				if err := it.Next(); err != nil {
+					d.error(err)
+				}
+				continue
+			}
+			d.mask = subMask
+		}
 		value, err := it.Value()
 		if err != nil {
 			d.error(err)
@@ -624,6 +1092,7 @@ func (d *decodeState) objectInterface(data Slice) map[string]interface{} {
 
 		// Read value.
 		m[keyStr] = d.valueInterface(value)
+		d.mask = mask
 
 		// Move to next field
 		if err := it.Next(); err != nil {
@@ -686,6 +1155,18 @@ func (d *decodeState) literalInterface(data Slice) interface{} {
 		}
 		return v
 
+	case MinKey:
+		return MinKeySentinel{}
+
+	case MaxKey:
+		return MaxKeySentinel{}
+
+	case Illegal:
+		return IllegalSentinel{}
+
+	case None:
+		return NoneSentinel{}
+
 	default: // ??
 		d.error(fmt.Errorf("unknown literal type: %s", data.Type()))
 		return nil
@@ -724,6 +1205,16 @@ func (d *decodeState) literalStore(item Slice, v reflect.Value, fromQuoted bool)
 		return
 	}
 	if ut != nil {
+		if bu, ok := ut.(encoding.BinaryUnmarshaler); ok && item.IsBinary() {
+			value, err := item.GetBinary()
+			if err != nil {
+				d.error(err)
+			}
+			if err := bu.UnmarshalBinary(value); err != nil {
+				d.error(err)
+			}
+			return
+		}
 		if !item.IsString() {
 			//if item[0] != '"' {
 			if fromQuoted {
@@ -999,6 +1490,14 @@ func (d *decodeState) literalStore(item Slice, v reflect.Value, fromQuoted bool)
 		if err != nil {
 			d.error(err)
 		}
+		if v.CanAddr() {
+			if bu, ok := v.Addr().Interface().(encoding.BinaryUnmarshaler); ok {
+				if err := bu.UnmarshalBinary(value); err != nil {
+					d.error(err)
+				}
+				break
+			}
+		}
 		switch v.Kind() {
 		default:
 			d.saveError(&UnmarshalTypeError{Value: "string", Type: v.Type()})
@@ -1016,6 +1515,13 @@ func (d *decodeState) literalStore(item Slice, v reflect.Value, fromQuoted bool)
 			}
 		}
 
+	case MinKey, MaxKey, Illegal, None:
+		if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(d.literalInterface(item)))
+		} else {
+			d.saveError(&UnmarshalTypeError{Value: item.Type().String(), Type: v.Type()})
+		}
+
 	default: // number
 		d.error(fmt.Errorf("Unknown type %s", item.Type()))
 	}
@@ -0,0 +1,58 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+import "strings"
+
+// fieldMask is a tree of attribute names selected for decoding.
+// A nil fieldMask for an attribute selects the attribute with all of its content.
+type fieldMask map[string]fieldMask
+
+// newFieldMask creates a fieldMask from the given list of '.' separated attribute paths.
+func newFieldMask(paths []string) fieldMask {
+	m := fieldMask{}
+	for _, p := range paths {
+		m.add(strings.Split(p, "."))
+	}
+	return m
+}
+
+// add selects the given attribute path in the mask.
+func (m fieldMask) add(path []string) {
+	name := path[0]
+	if len(path) == 1 {
+		// Select entire attribute
+		m[name] = nil
+		return
+	}
+	sub, found := m[name]
+	if found && sub == nil {
+		// Entire attribute already selected
+		return
+	}
+	if !found {
+		sub = fieldMask{}
+		m[name] = sub
+	}
+	sub.add(path[1:])
+}
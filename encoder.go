@@ -29,6 +29,7 @@ import (
 	"bytes"
 	"encoding"
 	"encoding/json"
+	"fmt"
 	"io"
 	"reflect"
 	"runtime"
@@ -39,8 +40,12 @@ import (
 
 // An Encoder encodes Go structures into velocypack values written to an output stream.
 type Encoder struct {
-	b Builder
-	w io.Writer
+	b       Builder
+	w       io.Writer
+	options EncoderOptions
+	cache   *typeEncoderCache // Encoders for options.TypeEncoders
+	array   bool              // Set when a top-level array is open
+	stream  *arrayStream      // Set when the open top-level array is streamed to w
 }
 
 // Marshaler is implemented by types that can convert themselves into Velocypack.
@@ -48,16 +53,60 @@ type Marshaler interface {
 	MarshalVPack() (Slice, error)
 }
 
+// BuilderMarshaler is implemented by types that can add themselves to a Builder.
+// MarshalVPackTo must add exactly one value to the given builder.
+// Unlike Marshaler, no intermediate Slice has to be allocated.
+type BuilderMarshaler interface {
+	MarshalVPackTo(b *Builder) error
+}
+
+// EncoderOptions contains options that control the encoding of Go values.
+type EncoderOptions struct {
+	// If set, maps with keys that cannot be used as object keys (such as struct keys)
+	// are encoded as an array of [key, value] pairs.
+	// Otherwise encoding such maps results in an UnsupportedTypeError.
+	MapKeyPairs bool
+	// TypeEncoders contains functions used to encode values of specific types,
+	// taking precedence over all other encodings of these types.
+	// Each function must add exactly one value to the given builder.
+	// An Encoder builds the encoders for its TypeEncoders once and reuses them,
+	// so the map must not be modified while the Encoder is in use.
+	// Marshal builds them on every call, so prefer an Encoder
+	// when encoding many values with the same TypeEncoders.
+	TypeEncoders map[reflect.Type]func(*Builder, reflect.Value) error
+	// If set, values implementing both encoding.TextMarshaler and encoding.BinaryMarshaler
+	// are encoded as String using MarshalText, like encoding/json does.
+	// By default such values (e.g. netip.Addr) are encoded as Binary using MarshalBinary.
+	// Values implementing only encoding.BinaryMarshaler are always encoded as Binary.
+	PreferTextMarshaler bool
+	// If set, encoding values that nest arrays and objects deeper than this
+	// results in a BuilderMaxDepthExceededError.
+	MaxDepth int
+	// If set, encoding values that result in more than this number of bytes
+	// results in a BuilderMaxSizeExceededError.
+	MaxSize ValueLength
+}
+
 // NewEncoder creates a new Encoder that writes output to the given writer.
-func NewEncoder(w io.Writer) *Encoder {
-	return &Encoder{
+func NewEncoder(w io.Writer, options ...EncoderOptions) *Encoder {
+	e := &Encoder{
 		w: w,
 	}
+	if len(options) > 0 {
+		e.options = options[0]
+		e.b.MaxDepth = e.options.MaxDepth
+		e.b.MaxSize = e.options.MaxSize
+	}
+	e.cache = encoderCacheFor(e.options.TypeEncoders)
+	return e
 }
 
 // Marshal writes the Velocypack encoding of v to a buffer and returns that buffer.
 //
 // Marshal traverses the value v recursively.
+// If an encountered value implements the BuilderMarshaler interface
+// and is not a nil pointer, Marshal calls its MarshalVPackTo method
+// to add its Velocypack directly to the output.
 // If an encountered value implements the Marshaler interface
 // and is not a nil pointer, Marshal calls its MarshalVPack method
 // to produce Velocypack.
@@ -67,6 +116,12 @@ func NewEncoder(w io.Writer) *Encoder {
 // If no MarshalVPack or MarshalJSON method is present but the
 // value implements encoding.TextMarshaler instead, Marshal calls
 // its MarshalText method and encodes the result as a Velocypack string.
+// If the value implements encoding.BinaryMarshaler instead, Marshal calls
+// its MarshalBinary method and encodes the result as Velocypack Binary data.
+// Values implementing both are encoded as Binary data, unless the
+// PreferTextMarshaler option is set.
+// MarshalBinary is also used for values that are not addressable,
+// when it has a pointer receiver.
 // The nil pointer exception is not strictly necessary
 // but mimics a similar, necessary exception in the behavior of
 // UnmarshalVPack.
@@ -86,9 +141,16 @@ func NewEncoder(w io.Writer) *Encoder {
 // Struct values encode as Velocypack objects.
 // The encoding follows the same rules as specified for json.Marshal.
 // This means that all `json` tags are fully supported.
+// In addition, the ",inline" option flattens the fields of a struct field
+// into the parent object, as if the struct was embedded anonymously.
+// A map field with string keys tagged ",inline" or ",rest" has all of its
+// entries added to the parent object, except entries with the name of
+// another field.
 //
 // Map values encode as Velocypack objects.
 // The encoding follows the same rules as specified for json.Marshal.
+// Maps with other key types encode as an array of [key, value] pairs,
+// if the MapKeyPairs option is set.
 //
 // Pointer values encode as the value pointed to.
 // A nil pointer encodes as the Null Velocypack value.
@@ -102,9 +164,9 @@ func NewEncoder(w io.Writer) *Encoder {
 //
 // Velocypack cannot represent cyclic data structures and Marshal does not
 // handle them. Passing cyclic structures to Marshal will result in
-// an infinite recursion.
+// an UnsupportedValueError.
 //
-func Marshal(v interface{}) (result Slice, err error) {
+func Marshal(v interface{}, options ...EncoderOptions) (result Slice, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if _, ok := r.(runtime.Error); ok {
@@ -116,13 +178,31 @@ func Marshal(v interface{}) (result Slice, err error) {
 			err = r.(error)
 		}
 	}()
-	var b Builder
-	reflectValue(&b, reflect.ValueOf(v), encoderOptions{})
-	return b.Slice()
+	var opts encoderOptions
+	if len(options) > 0 {
+		opts.EncoderOptions = options[0]
+	}
+	b := defaultBuilderPool.Get()
+	defer defaultBuilderPool.Put(b)
+	b.MaxDepth = opts.MaxDepth
+	b.MaxSize = opts.MaxSize
+	reflectValue(b, reflect.ValueOf(v), opts)
+	if err := b.checkMaxSize(); err != nil {
+		return nil, WithStack(err)
+	}
+	s, err := b.Slice()
+	if err != nil {
+		return nil, WithStack(err)
+	}
+	// The builder is returned to the pool, so copy its content.
+	result = make(Slice, len(s))
+	copy(result, s)
+	return result, nil
 }
 
 // Encode writes the Velocypack encoding of v to the stream.
 func (e *Encoder) Encode(v interface{}) (err error) {
+	var cp *BuilderCheckpoint
 	defer func() {
 		if r := recover(); r != nil {
 			if _, ok := r.(runtime.Error); ok {
@@ -133,9 +213,29 @@ func (e *Encoder) Encode(v interface{}) (err error) {
 			}
 			err = r.(error)
 		}
+		if err != nil && cp != nil {
+			// Remove the partially added value from the array.
+			e.b.Rollback(*cp)
+		}
 	}()
-	e.b.Clear()
-	reflectValue(&e.b, reflect.ValueOf(v), encoderOptions{})
+	if e.array && e.stream == nil {
+		// Add to the array that is being build in memory.
+		c := e.b.Checkpoint()
+		cp = &c
+		reflectValue(&e.b, reflect.ValueOf(v), encoderOptions{EncoderOptions: e.options, cache: e.cache})
+		return WithStack(e.b.checkMaxSize())
+	}
+	e.b.Reset()
+	reflectValue(&e.b, reflect.ValueOf(v), encoderOptions{EncoderOptions: e.options, cache: e.cache})
+	if err := e.b.checkMaxSize(); err != nil {
+		return WithStack(err)
+	}
+	if e.stream != nil {
+		if err := e.stream.add(e.b.buf); err != nil {
+			return WithStack(err)
+		}
+		return nil
+	}
 	if _, err := e.b.WriteTo(e.w); err != nil {
 		return WithStack(err)
 	}
@@ -166,38 +266,99 @@ func isEmptyValue(v reflect.Value) bool {
 }
 
 func reflectValue(b *Builder, v reflect.Value, options encoderOptions) {
-	valueEncoder(v)(b, v, options)
+	if options.cycles == nil {
+		options.cycles = &encodeCycleState{}
+	}
+	if options.cache == nil {
+		options.cache = encoderCacheFor(options.TypeEncoders)
+	}
+	options.cache.valueEncoder(v)(b, v, options)
 }
 
 type encoderOptions struct {
+	EncoderOptions
 	quoted bool
+	cycles *encodeCycleState // shared by all encoders of a single value
+	cache  *typeEncoderCache // encoders for the TypeEncoders hooks
+}
+
+// startDetectingCyclesAfter is the pointer nesting level after which
+// the encoder starts tracking the pointers it has seen, to detect cycles.
+// Below this level, the overhead of tracking is avoided.
+const startDetectingCyclesAfter = 1000
+
+// encodeCycleState is used to detect cyclic data structures while encoding.
+type encodeCycleState struct {
+	ptrLevel uint
+	ptrSeen  map[interface{}]struct{}
+}
+
+// enter is called before encoding the value referred to by v, identified by ptr.
+// It panics with an UnsupportedValueError when a cycle is detected.
+func (c *encodeCycleState) enter(v reflect.Value, ptr interface{}) {
+	c.ptrLevel++
+	if c.ptrLevel > startDetectingCyclesAfter {
+		if _, found := c.ptrSeen[ptr]; found {
+			panic(UnsupportedValueError{v, fmt.Sprintf("encountered a cycle via %s", v.Type())})
+		}
+		if c.ptrSeen == nil {
+			c.ptrSeen = make(map[interface{}]struct{})
+		}
+		c.ptrSeen[ptr] = struct{}{}
+	}
+}
+
+// leave is called after encoding the value referred to by v, identified by ptr.
+func (c *encodeCycleState) leave(ptr interface{}) {
+	if c.ptrLevel > startDetectingCyclesAfter {
+		delete(c.ptrSeen, ptr)
+	}
+	c.ptrLevel--
 }
 
 type encoderFunc func(b *Builder, v reflect.Value, options encoderOptions)
 
-var encoderCache struct {
+// typeEncoderCache holds the encoders that have been built for a set of type encoder hooks.
+type typeEncoderCache struct {
 	sync.RWMutex
-	m map[reflect.Type]encoderFunc
+	m     map[reflect.Type]encoderFunc
+	hooks map[reflect.Type]func(*Builder, reflect.Value) error
 }
 
-func valueEncoder(v reflect.Value) encoderFunc {
+// defaultEncoderCache holds the encoders built without type encoder hooks.
+var defaultEncoderCache = &typeEncoderCache{}
+
+// encoderCacheFor returns the encoder cache to use for the given type encoder hooks.
+// Without hooks, the shared default cache is returned.
+// Otherwise a new cache is returned, owned by the caller, so its encoders
+// are released together with the Encoder (or Marshal call) that uses them.
+func encoderCacheFor(hooks map[reflect.Type]func(*Builder, reflect.Value) error) *typeEncoderCache {
+	if len(hooks) == 0 {
+		return defaultEncoderCache
+	}
+	return &typeEncoderCache{hooks: hooks}
+}
+
+func (c *typeEncoderCache) valueEncoder(v reflect.Value) encoderFunc {
 	if !v.IsValid() {
 		return invalidValueEncoder
 	}
-	return typeEncoder(v.Type())
+	return c.typeEncoder(v.Type())
 }
 
 var (
-	marshalerType     = reflect.TypeOf(new(Marshaler)).Elem()
-	jsonMarshalerType = reflect.TypeOf(new(json.Marshaler)).Elem()
-	textMarshalerType = reflect.TypeOf(new(encoding.TextMarshaler)).Elem()
-	nullValue         = NewNullValue()
+	marshalerType        = reflect.TypeOf(new(Marshaler)).Elem()
+	builderMarshalerType = reflect.TypeOf(new(BuilderMarshaler)).Elem()
+	jsonMarshalerType    = reflect.TypeOf(new(json.Marshaler)).Elem()
+	textMarshalerType    = reflect.TypeOf(new(encoding.TextMarshaler)).Elem()
+	binaryMarshalerType  = reflect.TypeOf(new(encoding.BinaryMarshaler)).Elem()
+	nullValue            = NewNullValue()
 )
 
-func typeEncoder(t reflect.Type) encoderFunc {
-	encoderCache.RLock()
-	f := encoderCache.m[t]
-	encoderCache.RUnlock()
+func (c *typeEncoderCache) typeEncoder(t reflect.Type) encoderFunc {
+	c.RLock()
+	f := c.m[t]
+	c.RUnlock()
 	if f != nil {
 		return f
 	}
@@ -206,31 +367,46 @@ func typeEncoder(t reflect.Type) encoderFunc {
 	// indirect func before we build it. This type waits on the
 	// real func (f) to be ready and then calls it. This indirect
 	// func is only used for recursive types.
-	encoderCache.Lock()
-	if encoderCache.m == nil {
-		encoderCache.m = make(map[reflect.Type]encoderFunc)
+	c.Lock()
+	if c.m == nil {
+		c.m = make(map[reflect.Type]encoderFunc)
 	}
 	var wg sync.WaitGroup
 	wg.Add(1)
-	encoderCache.m[t] = func(b *Builder, v reflect.Value, options encoderOptions) {
+	c.m[t] = func(b *Builder, v reflect.Value, options encoderOptions) {
 		wg.Wait()
 		f(b, v, options)
 	}
-	encoderCache.Unlock()
+	c.Unlock()
 
 	// Compute fields without lock.
 	// Might duplicate effort but won't hold other computations back.
-	f = newTypeEncoder(t, true)
+	f = c.newTypeEncoder(t, true)
 	wg.Done()
-	encoderCache.Lock()
-	encoderCache.m[t] = f
-	encoderCache.Unlock()
+	c.Lock()
+	c.m[t] = f
+	c.Unlock()
 	return f
 }
 
 // newTypeEncoder constructs an encoderFunc for a type.
 // The returned encoder only checks CanAddr when allowAddr is true.
-func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
+func (c *typeEncoderCache) newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
+	if hook, found := c.hooks[t]; found {
+		return newHookEncoder(hook)
+	}
+	if c.hasPtrHook(t) {
+		// Pointer to a type with a hook, use the hook for the element.
+		return c.newPtrEncoder(t)
+	}
+	if t.Implements(builderMarshalerType) {
+		return builderMarshalerEncoder
+	}
+	if t.Kind() != reflect.Ptr && allowAddr {
+		if reflect.PtrTo(t).Implements(builderMarshalerType) {
+			return newCondAddrEncoder(addrBuilderMarshalerEncoder, c.newTypeEncoder(t, false))
+		}
+	}
 	if t.Implements(marshalerType) {
 		return marshalerEncoder
 	}
@@ -239,10 +415,10 @@ func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
 	}
 	if t.Kind() != reflect.Ptr && allowAddr {
 		if reflect.PtrTo(t).Implements(marshalerType) {
-			return newCondAddrEncoder(addrMarshalerEncoder, newTypeEncoder(t, false))
+			return newCondAddrEncoder(addrMarshalerEncoder, c.newTypeEncoder(t, false))
 		}
 		if reflect.PtrTo(t).Implements(jsonMarshalerType) {
-			return newCondAddrEncoder(addrJSONMarshalerEncoder, newTypeEncoder(t, false))
+			return newCondAddrEncoder(addrJSONMarshalerEncoder, c.newTypeEncoder(t, false))
 		}
 	}
 
@@ -251,9 +427,17 @@ func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
 	}
 	if t.Kind() != reflect.Ptr && allowAddr {
 		if reflect.PtrTo(t).Implements(textMarshalerType) {
-			return newCondAddrEncoder(addrTextMarshalerEncoder, newTypeEncoder(t, false))
+			return newCondAddrEncoder(addrTextMarshalerEncoder, c.newTypeEncoder(t, false))
 		}
 	}
+	if t.Implements(binaryMarshalerType) {
+		return binaryMarshalerEncoder
+	}
+	if t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(binaryMarshalerType) {
+		// Values that are not addressable are copied, so the encoding
+		// does not depend on how the value is reached.
+		return addrBinaryMarshalerEncoder
+	}
 
 	switch t.Kind() {
 	case reflect.Bool:
@@ -269,153 +453,247 @@ func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
 	case reflect.Interface:
 		return interfaceEncoder
 	case reflect.Struct:
-		return newStructEncoder(t)
+		return c.newStructEncoder(t)
 	case reflect.Map:
-		return newMapEncoder(t)
+		return c.newMapEncoder(t)
 	case reflect.Slice:
-		return newSliceEncoder(t)
+		return c.newSliceEncoder(t)
 	case reflect.Array:
-		return newArrayEncoder(t)
+		return c.newArrayEncoder(t)
 	case reflect.Ptr:
-		return newPtrEncoder(t)
+		return c.newPtrEncoder(t)
 	default:
 		return unsupportedTypeEncoder
 	}
 }
 
+// addValue adds the given value to the builder, panicking on failure
+// (e.g. when the builder exceeds its maximum size).
+func addValue(b *Builder, v Value) {
+	if err := b.addInternal(v); err != nil {
+		panic(err)
+	}
+}
+
 func invalidValueEncoder(b *Builder, v reflect.Value, options encoderOptions) {
-	b.addInternal(nullValue)
+	addValue(b, nullValue)
 }
 
 func marshalerEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 	if v.Kind() == reflect.Ptr && v.IsNil() {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
 	m, ok := v.Interface().(Marshaler)
 	if !ok {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
 	if vpack, err := m.MarshalVPack(); err != nil {
-		panic(&MarshalerError{v.Type(), err})
+		panic(MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalVPack"})
 	} else {
-		b.addInternal(NewSliceValue(vpack))
+		addValue(b, NewSliceValue(vpack))
 	}
 }
 
 func jsonMarshalerEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 	if v.Kind() == reflect.Ptr && v.IsNil() {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
 	m, ok := v.Interface().(json.Marshaler)
 	if !ok {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
 	if json, err := m.MarshalJSON(); err != nil {
-		panic(&MarshalerError{v.Type(), err})
+		panic(MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalJSON"})
 	} else {
 		// Convert JSON to vpack
 		if slice, err := ParseJSON(bytes.NewReader(json)); err != nil {
-			panic(&MarshalerError{v.Type(), err})
+			panic(MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalJSON"})
 		} else {
-			b.addInternal(NewSliceValue(slice))
+			addValue(b, NewSliceValue(slice))
+		}
+	}
+}
+
+func builderMarshalerEncoder(b *Builder, v reflect.Value, options encoderOptions) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		addValue(b, nullValue)
+		return
+	}
+	m, ok := v.Interface().(BuilderMarshaler)
+	if !ok {
+		addValue(b, nullValue)
+		return
+	}
+	addBuilderMarshaler(b, m, v.Type())
+}
+
+func addrBuilderMarshalerEncoder(b *Builder, v reflect.Value, options encoderOptions) {
+	va := v.Addr()
+	if va.IsNil() {
+		addValue(b, nullValue)
+		return
+	}
+	addBuilderMarshaler(b, va.Interface().(BuilderMarshaler), v.Type())
+}
+
+// addBuilderMarshaler calls m.MarshalVPackTo, checking that it adds exactly one complete value to b.
+func addBuilderMarshaler(b *Builder, m BuilderMarshaler, t reflect.Type) {
+	size, depth, keyWritten := b.buf.Len(), b.stack.Len(), b.keyWritten
+	indexLen := 0
+	if depth > 0 {
+		indexLen = len(b.index[depth-1])
+	}
+	if err := m.MarshalVPackTo(b); err != nil {
+		panic(MarshalerError{Type: t, Err: err, sourceFunc: "MarshalVPackTo"})
+	}
+	ok := b.stack.Len() == depth && !b.keyWritten && b.buf.Len() > size
+	if ok && depth > 0 {
+		// A value of an object entry is not added to the index, its key already is.
+		expected := indexLen + 1
+		if keyWritten {
+			expected = indexLen
 		}
+		ok = len(b.index[depth-1]) == expected
+	}
+	if ok {
+		l, err := Slice(b.buf[size:]).ByteSize()
+		ok = err == nil && l == b.buf.Len()-size
+	}
+	if !ok {
+		panic(MarshalerError{Type: t, Err: fmt.Errorf("must add exactly one value"), sourceFunc: "MarshalVPackTo"})
 	}
 }
 
 func addrMarshalerEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 	va := v.Addr()
 	if va.IsNil() {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
 	m := va.Interface().(Marshaler)
 	if vpack, err := m.MarshalVPack(); err != nil {
-		panic(&MarshalerError{Type: v.Type(), Err: err})
+		panic(MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalVPack"})
 	} else {
-		// copy VPack into buffer, checking validity.
-		b.buf.Write(vpack)
+		addValue(b, NewSliceValue(vpack))
 	}
 }
 
 func addrJSONMarshalerEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 	va := v.Addr()
 	if va.IsNil() {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
 	m := va.Interface().(json.Marshaler)
 	if json, err := m.MarshalJSON(); err != nil {
-		panic(&MarshalerError{Type: v.Type(), Err: err})
+		panic(MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalJSON"})
 	} else {
 		if slice, err := ParseJSON(bytes.NewReader(json)); err != nil {
-			panic(&MarshalerError{v.Type(), err})
+			panic(MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalJSON"})
 		} else {
-			// copy VPack into buffer, checking validity.
-			b.buf.Write(slice)
+			addValue(b, NewSliceValue(slice))
 		}
 	}
 }
 
 func textMarshalerEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 	if v.Kind() == reflect.Ptr && v.IsNil() {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
+	if !options.PreferTextMarshaler {
+		if m, ok := v.Interface().(encoding.BinaryMarshaler); ok {
+			addBinaryMarshaler(b, m, v.Type())
+			return
+		}
+	}
 	m := v.Interface().(encoding.TextMarshaler)
 	text, err := m.MarshalText()
 	if err != nil {
-		panic(&MarshalerError{v.Type(), err})
+		panic(MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalText"})
 	}
-	b.addInternal(NewStringValue(string(text)))
+	addValue(b, NewStringValue(string(text)))
 }
 
 func addrTextMarshalerEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 	va := v.Addr()
 	if va.IsNil() {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
+	if !options.PreferTextMarshaler {
+		if m, ok := va.Interface().(encoding.BinaryMarshaler); ok {
+			addBinaryMarshaler(b, m, v.Type())
+			return
+		}
+	}
 	m := va.Interface().(encoding.TextMarshaler)
 	text, err := m.MarshalText()
 	if err != nil {
-		panic(&MarshalerError{v.Type(), err})
+		panic(MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalText"})
+	}
+	addValue(b, NewStringValue(string(text)))
+}
+
+func binaryMarshalerEncoder(b *Builder, v reflect.Value, options encoderOptions) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		addValue(b, nullValue)
+		return
+	}
+	addBinaryMarshaler(b, v.Interface().(encoding.BinaryMarshaler), v.Type())
+}
+
+func addrBinaryMarshalerEncoder(b *Builder, v reflect.Value, options encoderOptions) {
+	if !v.CanAddr() {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	addBinaryMarshaler(b, v.Addr().Interface().(encoding.BinaryMarshaler), v.Type())
+}
+
+// addBinaryMarshaler adds the result of m.MarshalBinary to b as a Binary value.
+func addBinaryMarshaler(b *Builder, m encoding.BinaryMarshaler, t reflect.Type) {
+	data, err := m.MarshalBinary()
+	if err != nil {
+		panic(MarshalerError{Type: t, Err: err, sourceFunc: "MarshalBinary"})
 	}
-	b.addInternal(NewStringValue(string(text)))
+	addValue(b, NewBinaryValue(data))
 }
 
 func boolEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 	if options.quoted {
-		b.addInternal(NewStringValue(strconv.FormatBool(v.Bool())))
+		addValue(b, NewStringValue(strconv.FormatBool(v.Bool())))
 	} else {
-		b.addInternal(NewBoolValue(v.Bool()))
+		addValue(b, NewBoolValue(v.Bool()))
 	}
 }
 
 func intEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 	if options.quoted {
-		b.addInternal(NewStringValue(strconv.FormatInt(v.Int(), 10)))
+		addValue(b, NewStringValue(strconv.FormatInt(v.Int(), 10)))
 	} else {
-		b.addInternal(NewIntValue(v.Int()))
+		addValue(b, NewIntValue(v.Int()))
 	}
 }
 
 func uintEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 	if options.quoted {
-		b.addInternal(NewStringValue(strconv.FormatUint(v.Uint(), 10)))
+		addValue(b, NewStringValue(strconv.FormatUint(v.Uint(), 10)))
 	} else {
-		b.addInternal(NewUIntValue(v.Uint()))
+		addValue(b, NewUIntValue(v.Uint()))
 	}
 }
 
 func doubleEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 	if options.quoted {
-		b.addInternal(NewStringValue(formatDouble(v.Float())))
+		addValue(b, NewStringValue(formatDouble(v.Float())))
 	} else {
-		b.addInternal(NewDoubleValue(v.Float()))
+		addValue(b, NewDoubleValue(v.Float()))
 	}
 }
 
@@ -425,36 +703,69 @@ func stringEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 		raw, _ := json.Marshal(s)
 		s = string(raw)
 	}
-	b.addInternal(NewStringValue(s))
+	addValue(b, NewStringValue(s))
 }
 
 func interfaceEncoder(b *Builder, v reflect.Value, options encoderOptions) {
 	if v.IsNil() {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
 	vElem := v.Elem()
-	valueEncoder(vElem)(b, vElem, options)
+	options.cache.valueEncoder(vElem)(b, vElem, options)
+}
+
+// hasPtrHook returns true if t is a pointer to a type with an encoder hook.
+func (c *typeEncoderCache) hasPtrHook(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if _, found := c.hooks[t]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// newHookEncoder returns an encoder that calls the given type encoder hook.
+func newHookEncoder(hook func(*Builder, reflect.Value) error) encoderFunc {
+	return func(b *Builder, v reflect.Value, options encoderOptions) {
+		if err := hook(b, v); err != nil {
+			panic(MarshalerError{Type: v.Type(), Err: err, sourceFunc: "type encoder"})
+		}
+	}
 }
 
 func unsupportedTypeEncoder(b *Builder, v reflect.Value, options encoderOptions) {
-	panic(&UnsupportedTypeError{v.Type()})
+	panic(UnsupportedTypeError{v.Type()})
 }
 
 type structEncoder struct {
-	fields    []field
-	fieldEncs []encoderFunc
+	fields        []field
+	fieldEncs     []encoderFunc
+	names         map[string]struct{} // names of all fields, used to filter the rest field
+	discriminator *polymorphicName    // discriminator of registered polymorphic types
 }
 
 func (se *structEncoder) encode(b *Builder, v reflect.Value, options encoderOptions) {
 	if err := b.OpenObject(); err != nil {
 		panic(err)
 	}
+	if pn := se.discriminator; pn != nil {
+		if _, err := b.addInternalKey(pn.attribute); err != nil {
+			panic(err)
+		}
+		addValue(b, NewStringValue(pn.name))
+	}
 	for i, f := range se.fields {
 		fv := fieldByIndex(v, f.index)
 		if !fv.IsValid() || f.omitEmpty && isEmptyValue(fv) {
 			continue
 		}
+		if f.rest {
+			options.quoted = false
+			se.encodeRest(b, fv, se.fieldEncs[i], options)
+			continue
+		}
 		// Key
 		_, err := b.addInternalKey(f.name)
 		if err != nil {
@@ -469,14 +780,52 @@ func (se *structEncoder) encode(b *Builder, v reflect.Value, options encoderOpti
 	}
 }
 
-func newStructEncoder(t reflect.Type) encoderFunc {
+// encodeRest adds all entries of the given rest map to the open object,
+// skipping entries with the name of one of the other fields.
+func (se *structEncoder) encodeRest(b *Builder, v reflect.Value, elemEnc encoderFunc, options encoderOptions) {
+	// Extract and sort the keys.
+	keys := v.MapKeys()
+	sv := make(reflectWithStringSlice, len(keys))
+	for i, k := range keys {
+		sv[i].v = k
+		sv[i].s = k.String()
+	}
+	sort.Sort(sv)
+
+	for _, kv := range sv {
+		if _, found := se.names[kv.s]; found {
+			continue
+		}
+		// Key
+		_, err := b.addInternalKey(kv.s)
+		if err != nil {
+			panic(err)
+		}
+		// Value
+		elemEnc(b, v.MapIndex(kv.v), options)
+	}
+}
+
+func (c *typeEncoderCache) newStructEncoder(t reflect.Type) encoderFunc {
 	fields := cachedTypeFields(t)
 	se := &structEncoder{
 		fields:    fields,
 		fieldEncs: make([]encoderFunc, len(fields)),
+		names:     make(map[string]struct{}, len(fields)),
 	}
 	for i, f := range fields {
-		se.fieldEncs[i] = typeEncoder(typeByIndex(t, f.index))
+		if f.rest {
+			se.fieldEncs[i] = c.typeEncoder(typeByIndex(t, f.index).Elem())
+		} else {
+			se.fieldEncs[i] = c.typeEncoder(typeByIndex(t, f.index))
+			se.names[f.name] = struct{}{}
+		}
+	}
+	if pn, found := lookupPolymorphicName(t); found {
+		if _, found := se.names[pn.attribute]; !found {
+			se.discriminator = &pn
+			se.names[pn.attribute] = struct{}{}
+		}
 	}
 	return se.encode
 }
@@ -487,20 +836,23 @@ type mapEncoder struct {
 
 func (e *mapEncoder) encode(b *Builder, v reflect.Value, options encoderOptions) {
 	if v.IsNil() {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
 	if err := b.OpenObject(); err != nil {
 		panic(err)
 	}
 
+	ptr := v.Pointer()
+	options.cycles.enter(v, ptr)
+
 	// Extract and sort the keys.
 	keys := v.MapKeys()
 	sv := make(reflectWithStringSlice, len(keys))
 	for i, v := range keys {
 		sv[i].v = v
 		if err := sv[i].resolve(); err != nil {
-			panic(&MarshalerError{v.Type(), err})
+			panic(MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalText"})
 		}
 	}
 	sort.Sort(sv)
@@ -517,28 +869,90 @@ func (e *mapEncoder) encode(b *Builder, v reflect.Value, options encoderOptions)
 	if err := b.Close(); err != nil {
 		panic(err)
 	}
+	options.cycles.leave(ptr)
 }
 
-func newMapEncoder(t reflect.Type) encoderFunc {
+func (c *typeEncoderCache) newMapEncoder(t reflect.Type) encoderFunc {
 	switch t.Key().Kind() {
 	case reflect.String,
 		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 	default:
 		if !t.Key().Implements(textMarshalerType) {
-			return unsupportedTypeEncoder
+			me := &mapPairsEncoder{c.typeEncoder(t.Key()), c.typeEncoder(t.Elem())}
+			return me.encode
 		}
 	}
-	me := &mapEncoder{typeEncoder(t.Elem())}
+	me := &mapEncoder{c.typeEncoder(t.Elem())}
 	return me.encode
 }
 
+// mapPairsEncoder encodes maps with keys that cannot be used as object keys
+// as an array of [key, value] pairs, sorted by encoded key.
+type mapPairsEncoder struct {
+	keyEnc  encoderFunc
+	elemEnc encoderFunc
+}
+
+func (e *mapPairsEncoder) encode(b *Builder, v reflect.Value, options encoderOptions) {
+	if !options.MapKeyPairs {
+		panic(UnsupportedTypeError{v.Type()})
+	}
+	if v.IsNil() {
+		addValue(b, nullValue)
+		return
+	}
+
+	// Encode and sort the keys.
+	keys := v.MapKeys()
+	pairs := make(mapPairs, len(keys))
+	for i, k := range keys {
+		var kb Builder
+		e.keyEnc(&kb, k, options)
+		ks, err := kb.Slice()
+		if err != nil {
+			panic(err)
+		}
+		pairs[i] = mapPair{key: ks, v: k}
+	}
+	sort.Sort(pairs)
+
+	if err := b.OpenArray(); err != nil {
+		panic(err)
+	}
+	for _, p := range pairs {
+		if err := b.OpenArray(); err != nil {
+			panic(err)
+		}
+		addValue(b, NewSliceValue(p.key))
+		e.elemEnc(b, v.MapIndex(p.v), options)
+		if err := b.Close(); err != nil {
+			panic(err)
+		}
+	}
+	if err := b.Close(); err != nil {
+		panic(err)
+	}
+}
+
+type mapPair struct {
+	key Slice
+	v   reflect.Value
+}
+
+// mapPairs sorts map pairs by their encoded key.
+type mapPairs []mapPair
+
+func (p mapPairs) Len() int           { return len(p) }
+func (p mapPairs) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p mapPairs) Less(i, j int) bool { return bytes.Compare(p[i].key, p[j].key) < 0 }
+
 func encodeByteSlice(b *Builder, v reflect.Value, options encoderOptions) {
 	if v.IsNil() {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
-	b.addInternal(NewBinaryValue(v.Bytes()))
+	addValue(b, NewBinaryValue(v.Bytes()))
 }
 
 // sliceEncoder just wraps an arrayEncoder, checking to make sure the value isn't nil.
@@ -548,21 +962,29 @@ type sliceEncoder struct {
 
 func (se *sliceEncoder) encode(b *Builder, v reflect.Value, options encoderOptions) {
 	if v.IsNil() {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
+	// A slice is identified by its data pointer and length,
+	// since subslices may share the same data.
+	ptr := struct {
+		ptr uintptr
+		len int
+	}{v.Pointer(), v.Len()}
+	options.cycles.enter(v, ptr)
 	se.arrayEnc(b, v, options)
+	options.cycles.leave(ptr)
 }
 
-func newSliceEncoder(t reflect.Type) encoderFunc {
+func (c *typeEncoderCache) newSliceEncoder(t reflect.Type) encoderFunc {
 	// Byte slices get special treatment; arrays don't.
 	if t.Elem().Kind() == reflect.Uint8 {
 		p := reflect.PtrTo(t.Elem())
-		if !p.Implements(marshalerType) && !p.Implements(jsonMarshalerType) && !p.Implements(textMarshalerType) {
+		if !p.Implements(builderMarshalerType) && !p.Implements(marshalerType) && !p.Implements(jsonMarshalerType) && !p.Implements(textMarshalerType) && !p.Implements(binaryMarshalerType) {
 			return encodeByteSlice
 		}
 	}
-	enc := &sliceEncoder{newArrayEncoder(t)}
+	enc := &sliceEncoder{c.newArrayEncoder(t)}
 	return enc.encode
 }
 
@@ -583,8 +1005,8 @@ func (ae *arrayEncoder) encode(b *Builder, v reflect.Value, options encoderOptio
 	}
 }
 
-func newArrayEncoder(t reflect.Type) encoderFunc {
-	enc := &arrayEncoder{typeEncoder(t.Elem())}
+func (c *typeEncoderCache) newArrayEncoder(t reflect.Type) encoderFunc {
+	enc := &arrayEncoder{c.typeEncoder(t.Elem())}
 	return enc.encode
 }
 
@@ -594,14 +1016,17 @@ type ptrEncoder struct {
 
 func (pe *ptrEncoder) encode(b *Builder, v reflect.Value, options encoderOptions) {
 	if v.IsNil() {
-		b.addInternal(nullValue)
+		addValue(b, nullValue)
 		return
 	}
+	ptr := v.Interface()
+	options.cycles.enter(v, ptr)
 	pe.elemEnc(b, v.Elem(), options)
+	options.cycles.leave(ptr)
 }
 
-func newPtrEncoder(t reflect.Type) encoderFunc {
-	enc := &ptrEncoder{typeEncoder(t.Elem())}
+func (c *typeEncoderCache) newPtrEncoder(t reflect.Type) encoderFunc {
+	enc := &ptrEncoder{c.typeEncoder(t.Elem())}
 	return enc.encode
 }
 
@@ -47,6 +47,7 @@ type field struct {
 	typ       reflect.Type
 	omitEmpty bool
 	quoted    bool
+	rest      bool // set for the map collecting all attributes not matched by other fields
 }
 
 func fieldByIndex(v reflect.Value, index []int) reflect.Value {
@@ -136,6 +137,8 @@ func typeFields(t reflect.Type) []field {
 
 	// Fields found.
 	var fields []field
+	// Map field collecting the remaining attributes (,rest or ,inline map)
+	var rest *field
 
 	for len(next) > 0 {
 		current, next = next, current[:0]
@@ -184,8 +187,24 @@ func typeFields(t reflect.Type) []field {
 					}
 				}
 
+				// Record the first map with string keys tagged ,rest or ,inline
+				// as the field that collects the remaining attributes.
+				if (opts.Contains("rest") || opts.Contains("inline")) && ft.Kind() == reflect.Map && ft.Key().Kind() == reflect.String {
+					if rest == nil {
+						rest = &field{
+							name:  sf.Name,
+							index: index,
+							typ:   ft,
+							rest:  true,
+						}
+					}
+					continue
+				}
+
 				// Record found field and index sequence.
-				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+				// Structs tagged ,inline are explored like anonymous structs.
+				inline := opts.Contains("inline") && ft.Kind() == reflect.Struct
+				if !inline && (name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct) {
 					tagged := name != ""
 					if name == "" {
 						name = sf.Name
@@ -250,6 +269,10 @@ func typeFields(t reflect.Type) []field {
 	fields = out
 	sort.Sort(byIndex(fields))
 
+	if rest != nil {
+		fields = append(fields, fillField(*rest))
+	}
+
 	return fields
 }
 
@@ -0,0 +1,148 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+import "io"
+
+// OpenArray starts a top-level array in the output stream.
+// Every value passed to Encode is added to this array, until Close is called.
+//
+// When the underlying writer implements io.WriteSeeker, the elements are written
+// to it directly, using an array format with an 8-byte index table at the end.
+// Only the offsets of the elements are kept in memory.
+// The byte length of the array is patched into its header by Close.
+//
+// For all other writers, and for writers that cannot seek (such as a pipe),
+// the array is build in memory and written by Close.
+func (e *Encoder) OpenArray() error {
+	if e.array {
+		return WithStack(EncoderArrayAlreadyOpenError)
+	}
+	if ws, ok := e.w.(io.WriteSeeker); ok {
+		if start, err := ws.Seek(0, io.SeekCurrent); err == nil {
+			e.stream = &arrayStream{w: ws, start: start}
+			e.array = true
+			return nil
+		}
+		// Seeking is not supported, fall back to building the array in memory.
+	}
+	e.b.Reset()
+	if err := e.b.OpenArray(); err != nil {
+		return WithStack(err)
+	}
+	e.array = true
+	return nil
+}
+
+// Close finishes the top-level array opened with OpenArray.
+func (e *Encoder) Close() error {
+	if !e.array {
+		return WithStack(BuilderNeedOpenArrayError)
+	}
+	e.array = false
+	if stream := e.stream; stream != nil {
+		e.stream = nil
+		if err := stream.close(); err != nil {
+			return WithStack(err)
+		}
+		return nil
+	}
+	if err := e.b.Close(); err != nil {
+		return WithStack(err)
+	}
+	if _, err := e.b.WriteTo(e.w); err != nil {
+		return WithStack(err)
+	}
+	return nil
+}
+
+// arrayStream writes the elements of an array to a WriteSeeker as they come in.
+// The array is written in the 0x09 format: a head byte followed by an 8-byte byte length,
+// the elements, an 8-byte offset per element and the 8-byte number of elements.
+type arrayStream struct {
+	w      io.WriteSeeker
+	start  int64         // Position of the head byte in w
+	length ValueLength   // Number of bytes written so far
+	index  []ValueLength // Offsets of the elements relative to start
+}
+
+const arrayStreamHeaderSize = 9
+
+// add writes the given element.
+// The array header is written before the first element.
+func (s *arrayStream) add(element []byte) error {
+	if s.length == 0 {
+		var hdr [arrayStreamHeaderSize]byte
+		hdr[0] = 0x09
+		if err := s.write(hdr[:]); err != nil {
+			return WithStack(err)
+		}
+	}
+	s.index = append(s.index, s.length)
+	if err := s.write(element); err != nil {
+		return WithStack(err)
+	}
+	return nil
+}
+
+// close writes the index table and fixes the byte length in the header.
+func (s *arrayStream) close() error {
+	if len(s.index) == 0 {
+		// Empty array
+		if err := s.write([]byte{0x01}); err != nil {
+			return WithStack(err)
+		}
+		return nil
+	}
+	tail := make([]byte, 8*(len(s.index)+1))
+	for i, offset := range s.index {
+		setLength(tail[8*i:], offset, 8)
+	}
+	setLength(tail[8*len(s.index):], ValueLength(len(s.index)), 8)
+	if err := s.write(tail); err != nil {
+		return WithStack(err)
+	}
+	// Fix the byte length in the beginning
+	var byteLength [8]byte
+	setLength(byteLength[:], s.length, 8)
+	if _, err := s.w.Seek(s.start+1, io.SeekStart); err != nil {
+		return WithStack(err)
+	}
+	if _, err := s.w.Write(byteLength[:]); err != nil {
+		return WithStack(err)
+	}
+	if _, err := s.w.Seek(s.start+int64(s.length), io.SeekStart); err != nil {
+		return WithStack(err)
+	}
+	return nil
+}
+
+// write writes the given bytes to the underlying writer.
+func (s *arrayStream) write(p []byte) error {
+	n, err := s.w.Write(p)
+	s.length += ValueLength(n)
+	if err != nil {
+		return WithStack(err)
+	}
+	return nil
+}
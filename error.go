@@ -25,6 +25,7 @@ package velocypack
 import (
 	"errors"
 	"reflect"
+	"strconv"
 )
 
 // InvalidTypeError is returned when a Slice getter is called on a slice of a different type.
@@ -91,6 +92,26 @@ var (
 	BuilderNeedSubValueError = errors.New("builder need sub value")
 	// IsBuilderNeedSubValue returns true if the given error is an BuilderNeedSubValueError.
 	IsBuilderNeedSubValue = isCausedByFunc(BuilderNeedSubValueError)
+	// BuilderMaxDepthExceededError is returned when opening an array or object exceeds BuilderOptions.MaxDepth.
+	BuilderMaxDepthExceededError = errors.New("builder maximum depth exceeded")
+	// IsBuilderMaxDepthExceeded returns true if the given error is an BuilderMaxDepthExceededError.
+	IsBuilderMaxDepthExceeded = isCausedByFunc(BuilderMaxDepthExceededError)
+	// BuilderMaxSizeExceededError is returned when the generated bytes exceed BuilderOptions.MaxSize.
+	BuilderMaxSizeExceededError = errors.New("builder maximum size exceeded")
+	// IsBuilderMaxSizeExceeded returns true if the given error is an BuilderMaxSizeExceededError.
+	IsBuilderMaxSizeExceeded = isCausedByFunc(BuilderMaxSizeExceededError)
+	// BuilderBufferFullError is returned when the generated bytes do not fit in the buffer given to NewBuilderWithBuffer.
+	BuilderBufferFullError = errors.New("builder buffer full")
+	// IsBuilderBufferFull returns true if the given error is an BuilderBufferFullError.
+	IsBuilderBufferFull = isCausedByFunc(BuilderBufferFullError)
+	// BuilderInvalidCheckpointError is returned when Builder.Rollback is called with a checkpoint that is no longer valid.
+	BuilderInvalidCheckpointError = errors.New("builder invalid checkpoint")
+	// IsBuilderInvalidCheckpoint returns true if the given error is an BuilderInvalidCheckpointError.
+	IsBuilderInvalidCheckpoint = isCausedByFunc(BuilderInvalidCheckpointError)
+	// BuilderKeyNotFoundError is returned when Builder.RemoveKey or Builder.ReplaceKey is called with a key that the open object does not contain.
+	BuilderKeyNotFoundError = errors.New("builder key not found")
+	// IsBuilderKeyNotFound returns true if the given error is an BuilderKeyNotFoundError.
+	IsBuilderKeyNotFound = isCausedByFunc(BuilderKeyNotFoundError)
 	// InvalidUtf8SequenceError indicates an invalid UTF8 (string) sequence.
 	InvalidUtf8SequenceError = errors.New("invalid utf8 sequence")
 	// IsInvalidUtf8Sequence returns true if the given error is an InvalidUtf8SequenceError.
@@ -99,6 +120,18 @@ var (
 	NoJSONEquivalentError = errors.New("no JSON equivalent")
 	// IsNoJSONEquivalent returns true if the given error is an NoJSONEquivalentError.
 	IsNoJSONEquivalent = isCausedByFunc(NoJSONEquivalentError)
+	// EncoderArrayAlreadyOpenError is returned when Encoder.OpenArray is called while an array is already open.
+	EncoderArrayAlreadyOpenError = errors.New("encoder array already open")
+	// IsEncoderArrayAlreadyOpen returns true if the given error is an EncoderArrayAlreadyOpenError.
+	IsEncoderArrayAlreadyOpen = isCausedByFunc(EncoderArrayAlreadyOpenError)
+	// UnknownAttributeIDError is returned when an integer object key cannot be translated.
+	UnknownAttributeIDError = errors.New("unknown attribute ID")
+	// IsUnknownAttributeID returns true if the given error is an UnknownAttributeIDError.
+	IsUnknownAttributeID = isCausedByFunc(UnknownAttributeIDError)
+	// SliceTooLargeError indicates that a slice exceeds the configured maximum size.
+	SliceTooLargeError = errors.New("slice too large")
+	// IsSliceTooLarge returns true if the given error is an SliceTooLargeError.
+	IsSliceTooLarge = isCausedByFunc(SliceTooLargeError)
 )
 
 // isCausedByFunc creates an error test function.
@@ -126,13 +159,18 @@ func IsBuilderUnexpectedType(err error) bool {
 
 // MarshalerError is returned when a custom VPack Marshaler returns an error.
 type MarshalerError struct {
-	Type reflect.Type
-	Err  error
+	Type       reflect.Type
+	Err        error
+	sourceFunc string // Name of the method that failed, MarshalVPack if empty
 }
 
 // Error implements the error interface for MarshalerError.
 func (e MarshalerError) Error() string {
-	return "error calling MarshalVPack for type " + e.Type.String() + ": " + e.Err.Error()
+	srcFunc := e.sourceFunc
+	if srcFunc == "" {
+		srcFunc = "MarshalVPack"
+	}
+	return "error calling " + srcFunc + " for type " + e.Type.String() + ": " + e.Err.Error()
 }
 
 // IsMarshaler returns true if the given error is an MarshalerError.
@@ -157,6 +195,24 @@ func IsUnsupportedType(err error) bool {
 	return ok
 }
 
+// UnsupportedValueError is returned when a value is marshaled that cannot be marshaled,
+// such as a cyclic data structure.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+// Error implements the error interface for UnsupportedValueError.
+func (e UnsupportedValueError) Error() string {
+	return "unsupported value: " + e.Str
+}
+
+// IsUnsupportedValue returns true if the given error is an UnsupportedValueError.
+func IsUnsupportedValue(err error) bool {
+	_, ok := Cause(err).(UnsupportedValueError)
+	return ok
+}
+
 // An InvalidUnmarshalError describes an invalid argument passed to Unmarshal.
 // (The argument to Unmarshal must be a non-nil pointer.)
 type InvalidUnmarshalError struct {
@@ -187,19 +243,48 @@ type UnmarshalTypeError struct {
 	Type   reflect.Type // type of Go value it could not be assigned to
 	Struct string       // name of the struct type containing the field
 	Field  string       // name of the field holding the Go value
+	Path   string       // path of the value in the document - "users[3].address.zip"
+	Found  ValueType    // velocypack type of the value
+	Offset int64        // byte offset of the value in the slice being unmarshaled, -1 if unknown
 }
 
 func (e *UnmarshalTypeError) Error() string {
+	var msg string
 	if e.Struct != "" || e.Field != "" {
-		return "json: cannot unmarshal " + e.Value + " into Go struct field " + e.Struct + "." + e.Field + " of type " + e.Type.String()
+		msg = "json: cannot unmarshal " + e.Value + " into Go struct field " + e.Struct + "." + e.Field + " of type " + e.Type.String()
+	} else {
+		msg = "json: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String()
+	}
+	if e.Path != "" {
+		msg += " at " + e.Path
+	}
+	return msg
+}
+
+// UnmarshalTypeErrors is returned by Unmarshal when DecoderOptions.AllErrors is set.
+// It contains all values that could not be unmarshaled, in the order they were encountered.
+type UnmarshalTypeErrors []*UnmarshalTypeError
+
+func (e UnmarshalTypeErrors) Error() string {
+	switch len(e) {
+	case 0:
+		return "no errors"
+	case 1:
+		return e[0].Error()
+	default:
+		return e[0].Error() + " (and " + strconv.Itoa(len(e)-1) + " more errors)"
 	}
-	return "json: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String()
 }
 
-// IsUnmarshalType returns true if the given error is an UnmarshalTypeError.
+// IsUnmarshalType returns true if the given error is an UnmarshalTypeError
+// or an UnmarshalTypeErrors.
 func IsUnmarshalType(err error) bool {
-	_, ok := Cause(err).(*UnmarshalTypeError)
-	return ok
+	switch Cause(err).(type) {
+	case *UnmarshalTypeError, UnmarshalTypeErrors:
+		return true
+	default:
+		return false
+	}
 }
 
 // An ParseError is returned when JSON cannot be parsed correctly.
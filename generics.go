@@ -0,0 +1,98 @@
+//go:build go1.18
+// +build go1.18
+
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+// UnmarshalAs reads a value of type T from the given Velocypack encoded data slice.
+func UnmarshalAs[T any](s Slice) (T, error) {
+	var result T
+	if err := Unmarshal(s, &result); err != nil {
+		return result, WithStack(err)
+	}
+	return result, nil
+}
+
+// GetAs looks for the specified attribute path inside an Object
+// and reads its value as type T.
+// If the attribute path is not found, the zero value of T is returned.
+func GetAs[T any](s Slice, attributePath ...string) (T, error) {
+	var result T
+	value, err := s.Get(attributePath...)
+	if err != nil {
+		return result, WithStack(err)
+	}
+	if len(value) == 0 || value.IsNone() {
+		return result, nil
+	}
+	if err := Unmarshal(value, &result); err != nil {
+		return result, WithStack(err)
+	}
+	return result, nil
+}
+
+// TypedArrayIterator iterates over an array, yielding its values as type T.
+type TypedArrayIterator[T any] struct {
+	it *ArrayIterator
+}
+
+// NewTypedArrayIterator initializes an iterator at position 0 of the given array slice.
+func NewTypedArrayIterator[T any](s Slice) (*TypedArrayIterator[T], error) {
+	it, err := NewArrayIterator(s)
+	if err != nil {
+		return nil, WithStack(err)
+	}
+	return &TypedArrayIterator[T]{it: it}, nil
+}
+
+// IsValid returns true if the given position of the iterator is valid.
+func (i *TypedArrayIterator[T]) IsValid() bool {
+	return i.it.IsValid()
+}
+
+// IsFirst returns true if the current position is 0.
+func (i *TypedArrayIterator[T]) IsFirst() bool {
+	return i.it.IsFirst()
+}
+
+// Value returns the value of the current position of the iterator as type T.
+func (i *TypedArrayIterator[T]) Value() (T, error) {
+	var result T
+	s, err := i.it.Value()
+	if err != nil {
+		return result, WithStack(err)
+	}
+	if err := Unmarshal(s, &result); err != nil {
+		return result, WithStack(err)
+	}
+	return result, nil
+}
+
+// Next moves to the next position.
+func (i *TypedArrayIterator[T]) Next() error {
+	if err := i.it.Next(); err != nil {
+		return WithStack(err)
+	}
+	return nil
+}
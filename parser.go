@@ -36,6 +36,12 @@ type ParserOptions struct {
 	BuildUnindexedArrays bool
 	// If set, all Objects's will be unindexed.
 	BuildUnindexedObjects bool
+	// If set, parsing JSON that nests arrays and objects deeper than this
+	// results in a BuilderMaxDepthExceededError.
+	MaxDepth int
+	// If set, parsing JSON that results in more than this number of bytes
+	// of Velocypack results in a BuilderMaxSizeExceededError.
+	MaxSize ValueLength
 }
 
 // Parser is used to build VPack structures from JSON.
@@ -74,6 +80,7 @@ func ParseJSONFromUTF8(json []byte, options ...ParserOptions) (Slice, error) {
 
 // NewParser initializes a new Parser with JSON from the given reader and
 // it will store the parsers output in the given builder.
+// The MaxDepth and MaxSize options, if set, are applied to the given builder.
 func NewParser(r io.Reader, builder *Builder, options ...ParserOptions) *Parser {
 	d := json.NewDecoder(r)
 	d.UseNumber()
@@ -83,6 +90,12 @@ func NewParser(r io.Reader, builder *Builder, options ...ParserOptions) *Parser
 	}
 	if len(options) > 0 {
 		p.options = options[0]
+		if p.options.MaxDepth > 0 {
+			builder.MaxDepth = p.options.MaxDepth
+		}
+		if p.options.MaxSize > 0 {
+			builder.MaxSize = p.options.MaxSize
+		}
 	}
 	return p
 }
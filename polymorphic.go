@@ -0,0 +1,119 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// polymorphicInterface holds the registered concrete types of an interface type.
+type polymorphicInterface struct {
+	attribute string                  // name of the discriminator attribute
+	types     map[string]reflect.Type // discriminator value -> concrete type
+}
+
+// polymorphicName holds the discriminator of a registered concrete type.
+type polymorphicName struct {
+	attribute string
+	name      string
+}
+
+var polymorphicRegistry struct {
+	mu         sync.RWMutex
+	interfaces map[reflect.Type]*polymorphicInterface // interface type -> registered types
+	names      map[reflect.Type]polymorphicName       // concrete (non-pointer) type -> discriminator
+}
+
+// RegisterPolymorphicType registers the type of value as a concrete type of
+// the interface type pointed to by ifacePtr (e.g. (*Shape)(nil)).
+//
+// When unmarshaling an object into a nil value of the interface type, Unmarshal
+// reads the given discriminator attribute of the object and decodes the object
+// into a new value of the type registered under that name.
+// When marshaling a value of the registered type, Marshal adds the discriminator
+// attribute with the given name to the object, unless the type has a field with
+// the same name.
+//
+// All types of an interface must use the same discriminator attribute.
+// Types must be registered before they are marshaled for the first time,
+// typically in an init function.
+// RegisterPolymorphicType panics when called with invalid arguments.
+func RegisterPolymorphicType(ifacePtr interface{}, attribute, name string, value interface{}) {
+	pt := reflect.TypeOf(ifacePtr)
+	if pt == nil || pt.Kind() != reflect.Ptr || pt.Elem().Kind() != reflect.Interface {
+		panic(fmt.Sprintf("velocypack: RegisterPolymorphicType expects a pointer to an interface, got %v", pt))
+	}
+	it := pt.Elem()
+	t := reflect.TypeOf(value)
+	if t == nil || !t.Implements(it) {
+		panic(fmt.Sprintf("velocypack: type %v does not implement %v", t, it))
+	}
+	base := t
+	if base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+
+	polymorphicRegistry.mu.Lock()
+	defer polymorphicRegistry.mu.Unlock()
+	if polymorphicRegistry.interfaces == nil {
+		polymorphicRegistry.interfaces = make(map[reflect.Type]*polymorphicInterface)
+		polymorphicRegistry.names = make(map[reflect.Type]polymorphicName)
+	}
+	pi, found := polymorphicRegistry.interfaces[it]
+	if !found {
+		pi = &polymorphicInterface{
+			attribute: attribute,
+			types:     make(map[string]reflect.Type),
+		}
+		polymorphicRegistry.interfaces[it] = pi
+	} else if pi.attribute != attribute {
+		panic(fmt.Sprintf("velocypack: interface %v already uses discriminator attribute %q", it, pi.attribute))
+	}
+	if existing, found := pi.types[name]; found && existing != t {
+		panic(fmt.Sprintf("velocypack: name %q already registered for %v", name, existing))
+	}
+	pn := polymorphicName{attribute: attribute, name: name}
+	if existing, found := polymorphicRegistry.names[base]; found && existing != pn {
+		panic(fmt.Sprintf("velocypack: type %v already registered as %q", base, existing.name))
+	}
+	pi.types[name] = t
+	polymorphicRegistry.names[base] = pn
+}
+
+// lookupPolymorphicInterface returns the registered types of the given interface type,
+// or nil if no types have been registered.
+func lookupPolymorphicInterface(t reflect.Type) *polymorphicInterface {
+	polymorphicRegistry.mu.RLock()
+	defer polymorphicRegistry.mu.RUnlock()
+	return polymorphicRegistry.interfaces[t]
+}
+
+// lookupPolymorphicName returns the discriminator of the given concrete type.
+func lookupPolymorphicName(t reflect.Type) (polymorphicName, bool) {
+	polymorphicRegistry.mu.RLock()
+	defer polymorphicRegistry.mu.RUnlock()
+	pn, found := polymorphicRegistry.names[t]
+	return pn, found
+}
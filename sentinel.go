@@ -0,0 +1,94 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+// MinKeySentinel is the Go representation of a VelocyPack MinKey value.
+// It marshals to MinKey and MinKey values unmarshal into it.
+type MinKeySentinel struct{}
+
+// MarshalVPack implements Marshaler for MinKeySentinel.
+func (MinKeySentinel) MarshalVPack() (Slice, error) {
+	return MinKeySlice(), nil
+}
+
+// UnmarshalVPack implements Unmarshaler for MinKeySentinel.
+func (*MinKeySentinel) UnmarshalVPack(s Slice) error {
+	if err := s.AssertType(MinKey); err != nil {
+		return WithStack(err)
+	}
+	return nil
+}
+
+// MaxKeySentinel is the Go representation of a VelocyPack MaxKey value.
+// It marshals to MaxKey and MaxKey values unmarshal into it.
+type MaxKeySentinel struct{}
+
+// MarshalVPack implements Marshaler for MaxKeySentinel.
+func (MaxKeySentinel) MarshalVPack() (Slice, error) {
+	return MaxKeySlice(), nil
+}
+
+// UnmarshalVPack implements Unmarshaler for MaxKeySentinel.
+func (*MaxKeySentinel) UnmarshalVPack(s Slice) error {
+	if err := s.AssertType(MaxKey); err != nil {
+		return WithStack(err)
+	}
+	return nil
+}
+
+// IllegalSentinel is the Go representation of a VelocyPack Illegal value.
+// It marshals to Illegal and Illegal values unmarshal into it.
+type IllegalSentinel struct{}
+
+// MarshalVPack implements Marshaler for IllegalSentinel.
+func (IllegalSentinel) MarshalVPack() (Slice, error) {
+	return IllegalSlice(), nil
+}
+
+// UnmarshalVPack implements Unmarshaler for IllegalSentinel.
+func (*IllegalSentinel) UnmarshalVPack(s Slice) error {
+	if err := s.AssertType(Illegal); err != nil {
+		return WithStack(err)
+	}
+	return nil
+}
+
+// NoneSentinel is the Go representation of a VelocyPack None value,
+// such as returned by Slice.Get for a missing attribute.
+// None values unmarshal into it.
+// None cannot be stored in VelocyPack data, so marshaling a NoneSentinel
+// results in a BuilderUnexpectedTypeError.
+type NoneSentinel struct{}
+
+// MarshalVPack implements Marshaler for NoneSentinel.
+func (NoneSentinel) MarshalVPack() (Slice, error) {
+	return NoneSlice(), nil
+}
+
+// UnmarshalVPack implements Unmarshaler for NoneSentinel.
+func (*NoneSentinel) UnmarshalVPack(s Slice) error {
+	if err := s.AssertType(None); err != nil {
+		return WithStack(err)
+	}
+	return nil
+}
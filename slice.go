@@ -27,6 +27,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"math"
+	"strconv"
 	"time"
 )
 
@@ -88,7 +89,7 @@ func (s Slice) ByteSize() (ValueLength, error) {
 			return readVariableValueLength(s, 1, false), nil
 		}
 
-		vpackAssert(h > 0x00 && h <= 0x0e)
+		vpackAssert(h > 0x00 && h <= 0x12)
 		return ValueLength(readIntegerNonEmpty(s[1:], widthMap[h])), nil
 
 	case String:
@@ -553,8 +554,8 @@ func (s Slice) get(attribute string) (Slice, error) {
 	// otherwise we'll always use the linear search
 	const SortedSearchEntriesThreshold = ValueLength(4)
 
-	// bool const isSorted = (h >= 0x0b && h <= 0x0e);
-	if n >= SortedSearchEntriesThreshold && (h >= 0x0b && h <= 0x0e) {
+	// objects with an unsorted index table (0x0f - 0x12) always use the linear search
+	if n >= SortedSearchEntriesThreshold && s.IsSorted() {
 		// This means, we have to handle the special case n == 1 only
 		// in the linear search!
 		switch offsetSize {
@@ -921,7 +922,8 @@ func (s Slice) translateUnchecked() Slice {
 	id := s.getUIntUnchecked()
 	key := attributeTranslator.IDToString(id)
 	if key == "" {
-		return nil
+		// Unknown ID, use its decimal representation
+		key = strconv.FormatUint(id, 10)
 	}
 	return StringSlice(key)
 }
@@ -0,0 +1,193 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package velocypack
+
+import "sync"
+
+// AssembleArray creates an array that contains the members of all given arrays, in the given order.
+// The given arrays are typically built concurrently, e.g. by separate goroutines that each
+// use their own Builder. The members of the given arrays are collected and copied concurrently,
+// and the index table of the resulting array is computed once.
+func AssembleArray(parts ...Slice) (Slice, error) {
+	result, err := assemble(Array, parts)
+	return result, WithStack(err)
+}
+
+// AssembleObject creates an object that contains the attributes of all given objects.
+// The given objects are typically built concurrently, e.g. by separate goroutines that each
+// use their own Builder. The attributes of the given objects are collected and copied concurrently,
+// and the index table of the resulting object is computed once.
+// Returns a DuplicateAttributeNameError when a key is found in more than one of the given objects.
+func AssembleObject(parts ...Slice) (Slice, error) {
+	result, err := assemble(Object, parts)
+	return result, WithStack(err)
+}
+
+// assemblePart holds the members of an array, or the key/value pairs of an object, that is assembled.
+type assemblePart struct {
+	members []Slice
+	size    ValueLength // Total byte size of all members
+	offset  ValueLength // Position of the first member in the assembled value
+	first   int         // Position of the first member in the index vector of the assembled value
+	err     error
+}
+
+// assemble creates an array or object from the members of the given parts.
+func assemble(vt ValueType, parts []Slice) (Slice, error) {
+	collected := make([]assemblePart, len(parts))
+	forEachConcurrently(len(parts), func(i int) {
+		p := &collected[i]
+		p.members, p.size, p.err = collectMembers(vt, parts[i])
+	})
+
+	// Determine the position of all parts, directly after the 9 bytes
+	// reserved for the head of the assembled value.
+	offset := ValueLength(9)
+	count := 0
+	for i := range collected {
+		p := &collected[i]
+		if p.err != nil {
+			return nil, WithStack(p.err)
+		}
+		p.offset = offset
+		p.first = count
+		offset += p.size
+		count += len(p.members)
+	}
+
+	// Reserve space for the members and the index table
+	b := NewBuilder(uint(offset) + 8*uint(count) + 8)
+	if vt == Object {
+		b.CheckAttributeUniqueness = true
+		if err := b.OpenObject(); err != nil {
+			return nil, WithStack(err)
+		}
+	} else {
+		if err := b.OpenArray(); err != nil {
+			return nil, WithStack(err)
+		}
+	}
+	dst := b.buf.Grow(uint(offset - 9))
+	index := make(indexVector, count)
+	forEachConcurrently(len(collected), func(i int) {
+		p := &collected[i]
+		pos := p.offset
+		for j, m := range p.members {
+			copy(dst[pos-9:], m)
+			index[p.first+j] = pos
+			pos += ValueLength(len(m))
+		}
+	})
+	b.index[0] = index
+	if err := b.Close(); err != nil {
+		return nil, WithStack(err)
+	}
+	result, err := b.Slice()
+	if err != nil {
+		return nil, WithStack(err)
+	}
+	return result, nil
+}
+
+// collectMembers returns the members of the given array, or the key/value pairs of the given object,
+// together with their total byte size.
+func collectMembers(vt ValueType, s Slice) ([]Slice, ValueLength, error) {
+	if err := s.AssertType(vt); err != nil {
+		return nil, 0, WithStack(err)
+	}
+	n, err := s.Length()
+	if err != nil {
+		return nil, 0, WithStack(err)
+	}
+	members := make([]Slice, 0, n)
+	size := ValueLength(0)
+	if vt == Array {
+		it, err := NewArrayIterator(s)
+		if err != nil {
+			return nil, 0, WithStack(err)
+		}
+		for it.IsValid() {
+			value, err := it.Value()
+			if err != nil {
+				return nil, 0, WithStack(err)
+			}
+			valueSize, err := value.ByteSize()
+			if err != nil {
+				return nil, 0, WithStack(err)
+			}
+			members = append(members, value[:valueSize])
+			size += valueSize
+			if err := it.Next(); err != nil {
+				return nil, 0, WithStack(err)
+			}
+		}
+	} else {
+		it, err := NewObjectIterator(s, true)
+		if err != nil {
+			return nil, 0, WithStack(err)
+		}
+		for it.IsValid() {
+			key, err := it.Key(false)
+			if err != nil {
+				return nil, 0, WithStack(err)
+			}
+			keySize, err := key.ByteSize()
+			if err != nil {
+				return nil, 0, WithStack(err)
+			}
+			value, err := it.Value()
+			if err != nil {
+				return nil, 0, WithStack(err)
+			}
+			valueSize, err := value.ByteSize()
+			if err != nil {
+				return nil, 0, WithStack(err)
+			}
+			// The value directly follows the key
+			members = append(members, key[:keySize+valueSize])
+			size += keySize + valueSize
+			if err := it.Next(); err != nil {
+				return nil, 0, WithStack(err)
+			}
+		}
+	}
+	return members, size, nil
+}
+
+// forEachConcurrently calls f for all values 0 up to n in separate goroutines
+// and waits for all calls to finish.
+func forEachConcurrently(n int, f func(i int)) {
+	if n == 1 {
+		f(0)
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
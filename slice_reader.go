@@ -35,7 +35,7 @@ const (
 func SliceFromReader(r io.Reader) (Slice, error) {
 	if r, ok := r.(*bufio.Reader); ok {
 		// Buffered reader can use faster path.
-		return sliceFromBufReader(r)
+		return sliceFromBufReader(r, 0)
 	}
 	hdr := make(Slice, 1, maxByteSizeBytes)
 	// Read first byte
@@ -81,7 +81,7 @@ func SliceFromReader(r io.Reader) (Slice, error) {
 			return readRemaining(append(hdr, bytes...), l)
 		}
 
-		vpackAssert(h > 0x00 && h <= 0x0e)
+		vpackAssert(h > 0x00 && h <= 0x12)
 		l, bytes, err := readIntegerNonEmptyFromReader(r, widthMap[h])
 		if err != nil {
 			return nil, WithStack(err)
@@ -166,7 +166,8 @@ func SliceFromReader(r io.Reader) (Slice, error) {
 }
 
 // sliceFromBufReader reads a slice from the given buffered reader.
-func sliceFromBufReader(r *bufio.Reader) (Slice, error) {
+// If maxSize is not 0, slices larger than maxSize are rejected.
+func sliceFromBufReader(r *bufio.Reader, maxSize ValueLength) (Slice, error) {
 	// ByteSize is always found within first 16 bytes
 	hdr, err := r.Peek(maxByteSizeBytes)
 	if len(hdr) == 0 && err != nil {
@@ -181,17 +182,13 @@ func sliceFromBufReader(r *bufio.Reader) (Slice, error) {
 	if err != nil {
 		return nil, WithStack(err)
 	}
+	if maxSize != 0 && size > maxSize {
+		return nil, WithStack(SliceTooLargeError)
+	}
 	// Now that we know the size, read the entire slice
 	buf := make(Slice, size)
-	offset := 0
-	bytesRead := 0
-	for ValueLength(bytesRead) < size {
-		n, err := r.Read(buf[offset:])
-		bytesRead += n
-		offset += n
-		if err != nil && ValueLength(bytesRead) < size {
-			return nil, WithStack(err)
-		}
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, WithStack(err)
 	}
 	return buf, nil
 }
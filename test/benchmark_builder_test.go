@@ -23,6 +23,7 @@
 package test
 
 import (
+	"fmt"
 	"testing"
 
 	velocypack "github.com/arangodb/go-velocypack"
@@ -71,3 +72,108 @@ func BenchmarkBuilderObject2(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkBuilderObject1Typed(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		builder := velocypack.Builder{}
+		builder.OpenObject()
+		builder.AddKeyString("Name", "John Doe")
+		builder.AddKeyInt("Age", 42)
+		builder.Close()
+		if _, err := builder.Slice(); err != nil {
+			b.Errorf("Slice failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkBuilderObject1Fluent(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		builder := velocypack.Builder{}
+		f := builder.Fluent()
+		f.OpenObject().
+			AddKeyString("Name", "John Doe").
+			AddKeyInt("Age", 42).
+			Close()
+		if _, err := f.Slice(); err != nil {
+			b.Errorf("Slice failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkBuilderObjectUnsorted(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		builder := velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{BuildUnsortedObjects: true}}
+		builder.OpenObject()
+		builder.AddKeyString("Name", "John Doe")
+		builder.AddKeyString("FirstName", "John")
+		builder.AddKeyString("LastName", "Doe")
+		builder.AddKeyInt("Age", 42)
+		builder.Close()
+		if _, err := builder.Slice(); err != nil {
+			b.Errorf("Slice failed: %v", err)
+		}
+	}
+}
+
+// largeObjectKeys is the list of keys used by the large object benchmarks.
+var largeObjectKeys = func() []string {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%04d", i)
+	}
+	return keys
+}()
+
+func benchmarkBuilderLargeObjectUniqueness(b *testing.B, options velocypack.BuilderOptions) {
+	for i := 0; i < b.N; i++ {
+		builder := velocypack.Builder{BuilderOptions: options}
+		builder.OpenObject()
+		for j, k := range largeObjectKeys {
+			builder.AddKeyInt(k, int64(j))
+		}
+		if err := builder.Close(); err != nil {
+			b.Errorf("Close failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkBuilderLargeObjectUniqueness(b *testing.B) {
+	benchmarkBuilderLargeObjectUniqueness(b, velocypack.BuilderOptions{CheckAttributeUniqueness: true})
+}
+
+func BenchmarkBuilderLargeObjectUniquenessIndexKeys(b *testing.B) {
+	benchmarkBuilderLargeObjectUniqueness(b, velocypack.BuilderOptions{CheckAttributeUniqueness: true, IndexKeys: true})
+}
+
+func BenchmarkBuilderLargeObjectUniquenessUnsorted(b *testing.B) {
+	benchmarkBuilderLargeObjectUniqueness(b, velocypack.BuilderOptions{CheckAttributeUniqueness: true, BuildUnsortedObjects: true})
+}
+
+func BenchmarkBuilderLargeObjectUniquenessUnsortedIndexKeys(b *testing.B) {
+	benchmarkBuilderLargeObjectUniqueness(b, velocypack.BuilderOptions{CheckAttributeUniqueness: true, BuildUnsortedObjects: true, IndexKeys: true})
+}
+
+// benchmarkBuilderLargeObjectHasKey adds the keys of a large object, checking each key with HasKey before adding it.
+func benchmarkBuilderLargeObjectHasKey(b *testing.B, options velocypack.BuilderOptions) {
+	for i := 0; i < b.N; i++ {
+		builder := velocypack.Builder{BuilderOptions: options}
+		builder.OpenObject()
+		for j, k := range largeObjectKeys {
+			if found, err := builder.HasKey(k); err != nil {
+				b.Errorf("HasKey failed: %v", err)
+			} else if found {
+				b.Errorf("HasKey(%s) returned true", k)
+			}
+			builder.AddKeyInt(k, int64(j))
+		}
+		builder.Close()
+	}
+}
+
+func BenchmarkBuilderLargeObjectHasKey(b *testing.B) {
+	benchmarkBuilderLargeObjectHasKey(b, velocypack.BuilderOptions{})
+}
+
+func BenchmarkBuilderLargeObjectHasKeyIndexKeys(b *testing.B) {
+	benchmarkBuilderLargeObjectHasKey(b, velocypack.BuilderOptions{IndexKeys: true})
+}
@@ -0,0 +1,124 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+func TestBuilderRollbackNestedObject(t *testing.T) {
+	var b velocypack.Builder
+	must(b.OpenObject())
+	must(b.AddKeyValue("a", velocypack.NewIntValue(1)))
+	cp := b.Checkpoint()
+
+	// Speculatively add a nested object.
+	must(b.AddValue(velocypack.NewStringValue("nested")))
+	must(b.OpenObject())
+	must(b.AddKeyValue("x", velocypack.NewStringValue("foo")))
+	must(b.AddValue(velocypack.NewStringValue("deeper")))
+	must(b.OpenArray())
+	must(b.AddValue(velocypack.NewIntValue(7)))
+
+	must(b.Rollback(cp))
+	ASSERT_TRUE(b.IsOpenObject(), t)
+
+	must(b.AddKeyValue("b", velocypack.NewBoolValue(true)))
+	must(b.Close())
+	s := velocypack.Slice(mustBytes(b.Bytes()))
+	ASSERT_EQ(mustString(s.JSONString()), `{"a":1,"b":true}`, t)
+}
+
+func TestBuilderRollbackClosedValue(t *testing.T) {
+	var b velocypack.Builder
+	must(b.OpenArray())
+	must(b.AddValue(velocypack.NewIntValue(1)))
+	cp := b.Checkpoint()
+	must(b.OpenObject())
+	must(b.AddKeyValue("x", velocypack.NewIntValue(2)))
+	must(b.Close())
+	must(b.AddValue(velocypack.NewIntValue(3)))
+
+	must(b.Rollback(cp))
+	must(b.AddValue(velocypack.NewIntValue(4)))
+	must(b.Close())
+	s := velocypack.Slice(mustBytes(b.Bytes()))
+	ASSERT_EQ(mustString(s.JSONString()), `[1,4]`, t)
+}
+
+func TestBuilderRollbackKey(t *testing.T) {
+	var b velocypack.Builder
+	must(b.OpenObject())
+	cp := b.Checkpoint()
+	must(b.AddValue(velocypack.NewStringValue("key")))
+
+	// Rolling back removes the key, so a new key can be written.
+	must(b.Rollback(cp))
+	must(b.AddKeyValue("other", velocypack.NewIntValue(5)))
+	must(b.Close())
+	s := velocypack.Slice(mustBytes(b.Bytes()))
+	ASSERT_EQ(mustString(s.JSONString()), `{"other":5}`, t)
+}
+
+func TestBuilderRollbackTwice(t *testing.T) {
+	var b velocypack.Builder
+	must(b.OpenArray())
+	cp := b.Checkpoint()
+	must(b.AddValue(velocypack.NewIntValue(1)))
+	must(b.Rollback(cp))
+	must(b.AddValue(velocypack.NewIntValue(2)))
+	must(b.Rollback(cp))
+	must(b.Close())
+	s := velocypack.Slice(mustBytes(b.Bytes()))
+	ASSERT_EQ(mustString(s.JSONString()), `[]`, t)
+}
+
+func TestBuilderRollbackTopLevel(t *testing.T) {
+	var b velocypack.Builder
+	cp := b.Checkpoint()
+	must(b.OpenArray())
+	must(b.AddValue(velocypack.NewIntValue(1)))
+	must(b.Rollback(cp))
+	ASSERT_TRUE(b.IsEmpty(), t)
+	ASSERT_TRUE(b.IsClosed(), t)
+}
+
+func TestBuilderRollbackInvalid(t *testing.T) {
+	var b velocypack.Builder
+	must(b.OpenArray())
+	must(b.OpenArray())
+	cp := b.Checkpoint()
+	must(b.AddValue(velocypack.NewIntValue(1)))
+	must(b.Close())
+	// The array that was open at the checkpoint is closed.
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderInvalidCheckpoint, t)(b.Rollback(cp))
+
+	must(b.OpenArray())
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderInvalidCheckpoint, t)(b.Rollback(cp))
+
+	cp = b.Checkpoint()
+	b.Reset()
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderInvalidCheckpoint, t)(b.Rollback(cp))
+}
@@ -0,0 +1,164 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"strings"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+func TestBuilderWithBuffer(t *testing.T) {
+	buf := make([]byte, 64)
+	b := velocypack.NewBuilderWithBuffer(buf)
+	must(b.OpenObject())
+	must(b.AddKeyString("name", "John"))
+	must(b.AddKeyInt("age", 42))
+	must(b.Close())
+	s := mustSlice(b.Slice())
+	ASSERT_EQ(mustString(s.JSONString()), `{"age":42,"name":"John"}`, t)
+	// The slice is stored in the given buffer
+	ASSERT_EQ([]byte(s), buf[:len(s)], t)
+	ASSERT_TRUE(&s[0] == &buf[0], t)
+}
+
+func TestBuilderWithBufferFull(t *testing.T) {
+	buf := make([]byte, 32)
+	b := velocypack.NewBuilderWithBuffer(buf)
+	must(b.OpenArray())
+	must(b.AddString("short"))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderBufferFull, t)(b.AddString(strings.Repeat("x", 32)))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderBufferFull, t)(b.AddValue(velocypack.NewStringValue(strings.Repeat("x", 32))))
+
+	// The builder can be reused after a reset
+	b.Reset()
+	must(b.OpenArray())
+	must(b.AddString("short"))
+	must(b.Close())
+	s := mustSlice(b.Slice())
+	ASSERT_EQ(mustString(s.JSONString()), `["short"]`, t)
+	ASSERT_TRUE(&s[0] == &buf[0], t)
+}
+
+func TestBuilderWithBufferObject(t *testing.T) {
+	// An open object needs 9 bytes for its header, each entry needs 3 bytes.
+	buf := make([]byte, 14)
+	b := velocypack.NewBuilderWithBuffer(buf)
+	must(b.OpenObject())
+	must(b.AddKeyInt("a", 1))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderBufferFull, t)(b.AddKeyInt("b", 2))
+
+	buf = make([]byte, 15)
+	b = velocypack.NewBuilderWithBuffer(buf)
+	must(b.OpenObject())
+	must(b.AddKeyInt("a", 1))
+	must(b.AddKeyInt("b", 2))
+	must(b.Close())
+	s := mustSlice(b.Slice())
+	ASSERT_EQ(mustString(s.JSONString()), `{"a":1,"b":2}`, t)
+	ASSERT_TRUE(&s[0] == &buf[0], t)
+}
+
+func TestBuilderWithBufferRollback(t *testing.T) {
+	buf := make([]byte, 32)
+	b := velocypack.NewBuilderWithBuffer(buf)
+	must(b.OpenArray())
+	must(b.AddInt(1))
+	cp := b.Checkpoint()
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderBufferFull, t)(b.AddString(strings.Repeat("x", 64)))
+	must(b.Rollback(cp))
+	must(b.AddInt(2))
+	must(b.Close())
+	s := mustSlice(b.Slice())
+	ASSERT_EQ(mustString(s.JSONString()), `[1,2]`, t)
+	ASSERT_TRUE(&s[0] == &buf[0], t)
+}
+
+func TestBuilderWithBufferNoGrow(t *testing.T) {
+	// Values that do not fit are rejected before the buffer is grown.
+	buf := make([]byte, 17)
+	b := velocypack.NewBuilderWithBuffer(buf)
+	must(b.OpenObject())
+	must(b.AddKeyInt("a", 1))
+	long := strings.Repeat("x", 32)
+	longValue := velocypack.NewStringValue(long)
+	cp := b.Checkpoint()
+	allocs := testing.AllocsPerRun(10, func() {
+		ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderBufferFull, t)(b.AddKeyString("b", long))
+		must(b.Rollback(cp))
+		ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderBufferFull, t)(b.AddKeyValue("b", longValue))
+		must(b.Rollback(cp))
+		ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderBufferFull, t)(b.OpenArrayKey("b"))
+		must(b.Rollback(cp))
+	})
+	ASSERT_EQ(float64(0), allocs, t)
+
+	// A value that fits exactly does not grow the buffer either.
+	must(b.AddKeyUInt("b", 1000))
+	must(b.Close())
+	s := mustSlice(b.Slice())
+	ASSERT_EQ(mustString(s.JSONString()), `{"a":1,"b":1000}`, t)
+	ASSERT_TRUE(&s[0] == &buf[0], t)
+}
+
+func TestBuilderWithBufferSizes(t *testing.T) {
+	build := func(b *velocypack.Builder) error {
+		if err := b.OpenArray(); err != nil {
+			return err
+		}
+		if err := b.AddValue(velocypack.NewUIntValue(300)); err != nil {
+			return err
+		}
+		if err := b.OpenObject(); err != nil {
+			return err
+		}
+		for _, k := range []string{"c", "b", "a"} {
+			if err := b.AddKeyString(k, k); err != nil {
+				return err
+			}
+		}
+		if err := b.Close(); err != nil {
+			return err
+		}
+		if err := b.AddDouble(1.5); err != nil {
+			return err
+		}
+		return b.Close()
+	}
+	// Every capacity either fails or builds the complete value in the given buffer.
+	fits := 0
+	for n := 0; n < 64; n++ {
+		buf := make([]byte, n)
+		b := velocypack.NewBuilderWithBuffer(buf)
+		if err := build(b); err != nil {
+			ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderBufferFull, t)(err)
+			continue
+		}
+		fits++
+		s := mustSlice(b.Slice())
+		ASSERT_EQ(mustString(s.JSONString()), `[300,{"a":"a","b":"b","c":"c"},1.5]`, t)
+		ASSERT_TRUE(&s[0] == &buf[0], t)
+	}
+	ASSERT_TRUE(fits > 0, t)
+}
@@ -0,0 +1,191 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+func newIndexKeysBuilder() *velocypack.Builder {
+	return &velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{IndexKeys: true, CheckAttributeUniqueness: true}}
+}
+
+func TestBuilderIndexKeysHasKey(t *testing.T) {
+	b := newIndexKeysBuilder()
+	must(b.OpenObject())
+	must(b.AddKeyValue("foo", velocypack.NewIntValue(1)))
+	must(b.AddValue(velocypack.NewStringValue("bar")))
+	must(b.AddValue(velocypack.NewStringValue("baz")))
+	must(b.AddKeyString("qux", "quux"))
+	must(b.OpenArrayKey("arr"))
+	// Keys are tracked per object
+	must(b.OpenArray())
+	must(b.Close())
+	must(b.Close())
+	must(b.OpenObjectKey("obj"))
+	must(b.AddKeyBool("inner", true))
+	ASSERT_TRUE(mustBool(b.HasKey("inner")), t)
+	ASSERT_FALSE(mustBool(b.HasKey("foo")), t)
+	must(b.Close())
+
+	for _, k := range []string{"foo", "bar", "qux", "arr", "obj"} {
+		ASSERT_TRUE(mustBool(b.HasKey(k)), t)
+	}
+	for _, k := range []string{"baz", "quux", "inner", ""} {
+		ASSERT_FALSE(mustBool(b.HasKey(k)), t)
+	}
+	ASSERT_EQ(mustInt(mustSlice(b.GetKey("foo")).GetInt()), int64(1), t)
+	ASSERT_EQ(mustString(mustSlice(b.GetKey("bar")).GetString()), "baz", t)
+	ASSERT_EQ(mustString(mustSlice(b.GetKey("qux")).GetString()), "quux", t)
+	ASSERT_TRUE(mustSlice(b.GetKey("obj")).IsObject(), t)
+	ASSERT_TRUE(mustSlice(b.GetKey("notfound")).IsNone(), t)
+	must(b.Close())
+
+	s := mustSlice(b.Slice())
+	ASSERT_EQ(mustString(s.JSONString()), `{"arr":[[]],"bar":"baz","foo":1,"obj":{"inner":true},"qux":"quux"}`, t)
+}
+
+func TestBuilderIndexKeysDuplicate(t *testing.T) {
+	b := newIndexKeysBuilder()
+	must(b.OpenObject())
+	must(b.AddKeyInt("foo", 1))
+	must(b.AddKeyInt("bar", 2))
+	must(b.AddKeyInt("foo", 3))
+	// GetKey returns the first occurrence
+	ASSERT_EQ(mustInt(mustSlice(b.GetKey("foo")).GetInt()), int64(1), t)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsDuplicateAttributeName, t)(b.Close())
+}
+
+func TestBuilderIndexKeysLookupThenAdd(t *testing.T) {
+	// Keys added after the first lookup are added to the key set.
+	b := newIndexKeysBuilder()
+	must(b.OpenObject())
+	ASSERT_FALSE(mustBool(b.HasKey("foo")), t)
+	must(b.AddKeyInt("foo", 1))
+	ASSERT_TRUE(mustBool(b.HasKey("foo")), t)
+	ASSERT_FALSE(mustBool(b.HasKey("bar")), t)
+	must(b.AddKeyInt("bar", 2))
+	must(b.AddKeyInt("foo", 3))
+	ASSERT_EQ(mustInt(mustSlice(b.GetKey("bar")).GetInt()), int64(2), t)
+	ASSERT_EQ(mustInt(mustSlice(b.GetKey("foo")).GetInt()), int64(1), t)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsDuplicateAttributeName, t)(b.Close())
+}
+
+func TestBuilderIndexKeysDuplicateUnsorted(t *testing.T) {
+	b := &velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{IndexKeys: true, CheckAttributeUniqueness: true, BuildUnsortedObjects: true}}
+	must(b.OpenObject())
+	must(b.AddKeyInt("foo", 1))
+	must(b.AddKeyInt("bar", 2))
+	ASSERT_TRUE(mustBool(b.HasKey("bar")), t)
+	must(b.AddKeyInt("foo", 3))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsDuplicateAttributeName, t)(b.Close())
+}
+
+func TestBuilderIndexKeysDuplicateNested(t *testing.T) {
+	b := newIndexKeysBuilder()
+	must(b.OpenObject())
+	must(b.AddKeyInt("foo", 1))
+	must(b.OpenObjectKey("bar"))
+	must(b.AddKeyInt("foo", 2))
+	must(b.Close())
+	must(b.AddKeyInt("bar", 3))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsDuplicateAttributeName, t)(b.Close())
+}
+
+func TestBuilderIndexKeysRemoveLast(t *testing.T) {
+	b := newIndexKeysBuilder()
+	must(b.OpenObject())
+	must(b.AddKeyInt("foo", 1))
+	must(b.AddKeyInt("bar", 2))
+	must(b.RemoveLast())
+	ASSERT_TRUE(mustBool(b.HasKey("foo")), t)
+	ASSERT_FALSE(mustBool(b.HasKey("bar")), t)
+	// Adding the removed key again is not a duplicate
+	must(b.AddKeyInt("bar", 3))
+	ASSERT_EQ(mustInt(mustSlice(b.GetKey("bar")).GetInt()), int64(3), t)
+	must(b.Close())
+	ASSERT_EQ(mustString(mustSlice(b.Slice()).JSONString()), `{"bar":3,"foo":1}`, t)
+}
+
+func TestBuilderIndexKeysRollback(t *testing.T) {
+	b := newIndexKeysBuilder()
+	must(b.OpenObject())
+	must(b.AddKeyInt("foo", 1))
+	cp := b.Checkpoint()
+	must(b.AddKeyInt("bar", 2))
+	must(b.AddKeyInt("foo", 3))
+	must(b.Rollback(cp))
+	ASSERT_FALSE(mustBool(b.HasKey("bar")), t)
+	must(b.AddKeyInt("bar", 4))
+	must(b.Close())
+	ASSERT_EQ(mustString(mustSlice(b.Slice()).JSONString()), `{"bar":4,"foo":1}`, t)
+}
+
+func TestBuilderIndexKeysFailedValue(t *testing.T) {
+	b := newIndexKeysBuilder()
+	must(b.OpenObject())
+	must(b.AddKeyInt("foo", 1))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderUnexpectedType, t)(b.AddKeyValue("bar", velocypack.Value{}))
+	ASSERT_FALSE(mustBool(b.HasKey("bar")), t)
+	must(b.AddKeyInt("bar", 2))
+	must(b.Close())
+	ASSERT_EQ(mustString(mustSlice(b.Slice()).JSONString()), `{"bar":2,"foo":1}`, t)
+}
+
+func TestBuilderIndexKeysEnabledLater(t *testing.T) {
+	var b velocypack.Builder
+	must(b.OpenObject())
+	must(b.AddKeyInt("foo", 1))
+	must(b.AddKeyInt("bar", 2))
+	// The key set is built when it is first needed
+	b.IndexKeys = true
+	ASSERT_TRUE(mustBool(b.HasKey("foo")), t)
+	must(b.AddKeyInt("baz", 3))
+	ASSERT_TRUE(mustBool(b.HasKey("baz")), t)
+	ASSERT_FALSE(mustBool(b.HasKey("qux")), t)
+	must(b.Close())
+}
+
+func TestBuilderIndexKeysLarge(t *testing.T) {
+	b := newIndexKeysBuilder()
+	must(b.OpenObject())
+	for i := 0; i < 1000; i++ {
+		must(b.AddKeyInt(fmt.Sprintf("key%d", i), int64(i)))
+	}
+	for i := 0; i < 1000; i++ {
+		ASSERT_EQ(mustInt(mustSlice(b.GetKey(fmt.Sprintf("key%d", i))).GetInt()), int64(i), t)
+	}
+	must(b.Close())
+	s := mustSlice(b.Slice())
+	ASSERT_EQ(mustLength(s.Length()), velocypack.ValueLength(1000), t)
+
+	// The builder can be reused
+	b.Reset()
+	must(b.OpenObject())
+	must(b.AddKeyInt("key1", 1))
+	ASSERT_FALSE(mustBool(b.HasKey("key2")), t)
+	must(b.Close())
+}
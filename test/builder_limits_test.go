@@ -0,0 +1,141 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"strings"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+func TestBuilderMaxDepth(t *testing.T) {
+	b := velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{MaxDepth: 2}}
+	must(b.OpenArray())
+	must(b.OpenArray())
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxDepthExceeded, t)(b.OpenArray())
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxDepthExceeded, t)(b.OpenObject())
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxDepthExceeded, t)(b.AddValue(velocypack.NewArrayValue()))
+
+	// The builder can still be used at lower depths.
+	must(b.AddValue(velocypack.NewIntValue(1)))
+	must(b.Close())
+	must(b.Close())
+	s := velocypack.Slice(mustBytes(b.Bytes()))
+	ASSERT_EQ(mustString(s.JSONString()), "[[1]]", t)
+}
+
+func TestBuilderMaxSize(t *testing.T) {
+	b := velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{MaxSize: 32}}
+	must(b.OpenArray())
+	must(b.AddValue(velocypack.NewStringValue("short")))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxSizeExceeded, t)(b.AddValue(velocypack.NewStringValue(strings.Repeat("x", 64))))
+}
+
+func TestBuilderMaxSizeClose(t *testing.T) {
+	// The values fit, but the index table added by Close does not.
+	b := velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{MaxSize: 30}}
+	must(b.OpenArray())
+	for i := 0; i < 4; i++ {
+		must(b.AddValue(velocypack.NewStringValue("a")))
+		must(b.AddValue(velocypack.NewStringValue("bb")))
+	}
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxSizeExceeded, t)(b.Close())
+}
+
+func TestBuilderMaxSizeFits(t *testing.T) {
+	b := velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{MaxSize: 64, MaxDepth: 4}}
+	must(b.OpenObject())
+	must(b.AddKeyValue("a", velocypack.NewIntValue(1)))
+	must(b.AddKeyValue("b", velocypack.NewStringValue("foo")))
+	must(b.Close())
+	s := velocypack.Slice(mustBytes(b.Bytes()))
+	ASSERT_EQ(mustString(s.JSONString()), `{"a":1,"b":"foo"}`, t)
+}
+
+func TestParserMaxDepth(t *testing.T) {
+	json := strings.Repeat("[", 100) + strings.Repeat("]", 100)
+	_, err := velocypack.ParseJSONFromString(json, velocypack.ParserOptions{MaxDepth: 10})
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxDepthExceeded, t)(err)
+
+	s, err := velocypack.ParseJSONFromString(json, velocypack.ParserOptions{MaxDepth: 100})
+	ASSERT_NIL(err, t)
+	ASSERT_TRUE(s.IsArray(), t)
+}
+
+func TestParserMaxSize(t *testing.T) {
+	json := `["` + strings.Repeat("x", 1000) + `"]`
+	_, err := velocypack.ParseJSONFromString(json, velocypack.ParserOptions{MaxSize: 512})
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxSizeExceeded, t)(err)
+
+	_, err = velocypack.ParseJSONFromString(json, velocypack.ParserOptions{MaxSize: 2048})
+	ASSERT_NIL(err, t)
+}
+
+type LimitNode struct {
+	Child *LimitNode `json:"child,omitempty"`
+}
+
+func TestMarshalMaxDepth(t *testing.T) {
+	v := &LimitNode{Child: &LimitNode{Child: &LimitNode{}}}
+	_, err := velocypack.Marshal(v, velocypack.EncoderOptions{MaxDepth: 2})
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxDepthExceeded, t)(err)
+
+	_, err = velocypack.Marshal(v, velocypack.EncoderOptions{MaxDepth: 3})
+	ASSERT_NIL(err, t)
+}
+
+func TestMarshalMaxSize(t *testing.T) {
+	v := []string{"a", strings.Repeat("b", 100)}
+	_, err := velocypack.Marshal(v, velocypack.EncoderOptions{MaxSize: 64})
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxSizeExceeded, t)(err)
+
+	// A string that exceeds the limit is not silently dropped.
+	_, err = velocypack.Marshal(strings.Repeat("c", 100), velocypack.EncoderOptions{MaxSize: 64})
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxSizeExceeded, t)(err)
+
+	// The pooled builder does not keep the limit.
+	_, err = velocypack.Marshal(v)
+	ASSERT_NIL(err, t)
+}
+
+func TestMarshalMaxSizeIntermediate(t *testing.T) {
+	// Limits that are only exceeded half way through the values must fail,
+	// instead of returning the values that fit.
+	v := []interface{}{"a", 12345, "bbbbbbbb"}
+	for _, n := range []int{9, 10, 19, 20, 21, 22} {
+		_, err := velocypack.Marshal(v, velocypack.EncoderOptions{MaxSize: velocypack.ValueLength(n)})
+		ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxSizeExceeded, t)(err)
+	}
+
+	// Every limit either fails or yields the complete value.
+	nested := []interface{}{"a", 12345, "bbbbbbbb", map[string]interface{}{"c": true}, []string{"d"}}
+	for n := 1; n < 64; n++ {
+		s, err := velocypack.Marshal(nested, velocypack.EncoderOptions{MaxSize: velocypack.ValueLength(n)})
+		if err != nil {
+			ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxSizeExceeded, t)(err)
+			continue
+		}
+		ASSERT_EQ(`["a",12345,"bbbbbbbb",{"c":true},["d"]]`, mustString(s.JSONString()), t)
+	}
+}
@@ -0,0 +1,137 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"strings"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+func buildModifyKeyObject(b *velocypack.Builder) {
+	must(b.OpenObject())
+	must(b.AddKeyString("name", "default"))
+	must(b.AddKeyInt("age", 0))
+	must(b.OpenObjectKey("address"))
+	must(b.AddKeyString("city", "unknown"))
+	must(b.Close())
+	must(b.OpenArrayKey("tags"))
+	must(b.AddString("a"))
+	must(b.Close())
+}
+
+func TestBuilderRemoveKey(t *testing.T) {
+	for _, indexKeys := range []bool{false, true} {
+		b := velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{IndexKeys: indexKeys}}
+		buildModifyKeyObject(&b)
+		must(b.RemoveKey("age"))
+		ASSERT_FALSE(mustBool(b.HasKey("age")), t)
+		must(b.RemoveKey("address"))
+		ASSERT_EQ(mustString(mustSlice(b.GetKey("name")).GetString()), "default", t)
+		ASSERT_TRUE(mustSlice(b.GetKey("tags")).IsArray(), t)
+		must(b.AddKeyInt("age", 42))
+		must(b.Close())
+		ASSERT_EQ(mustString(mustSlice(b.Slice()).JSONString()), `{"age":42,"name":"default","tags":["a"]}`, t)
+	}
+}
+
+func TestBuilderRemoveKeyLast(t *testing.T) {
+	var b velocypack.Builder
+	buildModifyKeyObject(&b)
+	must(b.RemoveKey("tags"))
+	must(b.RemoveKey("name"))
+	must(b.RemoveKey("address"))
+	must(b.RemoveKey("age"))
+	must(b.Close())
+	ASSERT_EQ(mustString(mustSlice(b.Slice()).JSONString()), `{}`, t)
+}
+
+func TestBuilderRemoveKeyDuplicate(t *testing.T) {
+	var b velocypack.Builder
+	must(b.OpenObject())
+	must(b.AddKeyInt("foo", 1))
+	must(b.AddKeyInt("foo", 2))
+	must(b.RemoveKey("foo"))
+	ASSERT_EQ(mustInt(mustSlice(b.GetKey("foo")).GetInt()), int64(2), t)
+	must(b.Close())
+	ASSERT_EQ(mustString(mustSlice(b.Slice()).JSONString()), `{"foo":2}`, t)
+}
+
+func TestBuilderReplaceKey(t *testing.T) {
+	for _, indexKeys := range []bool{false, true} {
+		b := velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{IndexKeys: indexKeys}}
+		buildModifyKeyObject(&b)
+		// Larger value
+		must(b.ReplaceKey("name", velocypack.NewStringValue("John Doe, the one and only")))
+		// Smaller value
+		must(b.ReplaceKey("address", velocypack.NewIntValue(7)))
+		// Compound value from a slice
+		must(b.ReplaceKey("age", velocypack.NewSliceValue(mustSlice(velocypack.ParseJSONFromString(`{"years":42}`)))))
+		ASSERT_EQ(mustInt(mustSlice(b.GetKey("address")).GetInt()), int64(7), t)
+		ASSERT_TRUE(mustSlice(b.GetKey("tags")).IsArray(), t)
+		must(b.AddKeyBool("extra", true))
+		must(b.Close())
+		ASSERT_EQ(mustString(mustSlice(b.Slice()).JSONString()), `{"address":7,"age":{"years":42},"extra":true,"name":"John Doe, the one and only","tags":["a"]}`, t)
+	}
+}
+
+func TestBuilderModifyKeyErrors(t *testing.T) {
+	var b velocypack.Builder
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderNeedOpenObject, t)(b.RemoveKey("foo"))
+	must(b.OpenArray())
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderNeedOpenObject, t)(b.ReplaceKey("foo", velocypack.NewIntValue(1)))
+	must(b.Close())
+
+	b.Clear()
+	buildModifyKeyObject(&b)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderKeyNotFound, t)(b.RemoveKey("foo"))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderKeyNotFound, t)(b.ReplaceKey("foo", velocypack.NewIntValue(1)))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderUnexpectedType, t)(b.ReplaceKey("name", velocypack.NewObjectValue()))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderUnexpectedType, t)(b.ReplaceKey("name", velocypack.Value{}))
+	must(b.AddValue(velocypack.NewStringValue("key")))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderKeyAlreadyWritten, t)(b.RemoveKey("name"))
+	must(b.AddValue(velocypack.NewNullValue()))
+	must(b.Close())
+	// Failed calls leave the object intact
+	ASSERT_EQ(mustString(mustSlice(b.Slice()).JSONString()), `{"address":{"city":"unknown"},"age":0,"key":null,"name":"default","tags":["a"]}`, t)
+}
+
+func TestBuilderReplaceKeyMaxSize(t *testing.T) {
+	b := velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{MaxSize: 100}}
+	buildModifyKeyObject(&b)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderMaxSizeExceeded, t)(b.ReplaceKey("name", velocypack.NewStringValue(strings.Repeat("x", 100))))
+	ASSERT_EQ(mustString(mustSlice(b.GetKey("name")).GetString()), "default", t)
+	must(b.Close())
+}
+
+func TestBuilderModifyKeyInvalidatesCheckpoint(t *testing.T) {
+	var b velocypack.Builder
+	buildModifyKeyObject(&b)
+	cp := b.Checkpoint()
+	must(b.RemoveKey("name"))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderInvalidCheckpoint, t)(b.Rollback(cp))
+	cp = b.Checkpoint()
+	must(b.ReplaceKey("age", velocypack.NewIntValue(1)))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderInvalidCheckpoint, t)(b.Rollback(cp))
+}
@@ -0,0 +1,127 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"sync"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+func TestBuilderReset(t *testing.T) {
+	var b velocypack.Builder
+	must(b.OpenObject())
+	must(b.AddKeyValue("a", velocypack.NewStringValue("a long string value to fill the buffer")))
+	must(b.AddKeyValue("b", velocypack.NewIntValue(42)))
+	must(b.Close())
+	first := mustBytes(b.Bytes())
+	firstCap := cap(first)
+
+	b.Reset()
+	ASSERT_TRUE(b.IsEmpty(), t)
+	ASSERT_TRUE(b.IsClosed(), t)
+
+	must(b.OpenArray())
+	must(b.AddValue(velocypack.NewIntValue(1)))
+	must(b.AddValue(velocypack.NewIntValue(2)))
+	must(b.Close())
+	second := mustBytes(b.Bytes())
+	ASSERT_EQ(cap(second), firstCap, t)
+
+	s := velocypack.Slice(second)
+	ASSERT_EQ(mustString(s.JSONString()), "[1,2]", t)
+}
+
+func TestBuilderResetOpen(t *testing.T) {
+	var b velocypack.Builder
+	must(b.OpenObject())
+	must(b.AddKeyValue("a", velocypack.NewIntValue(1)))
+	must(b.AddValue(velocypack.NewStringValue("b")))
+	must(b.OpenArray())
+	ASSERT_FALSE(b.IsClosed(), t)
+	b.Reset()
+
+	must(b.OpenObject())
+	must(b.AddKeyValue("x", velocypack.NewBoolValue(true)))
+	must(b.Close())
+	s := velocypack.Slice(mustBytes(b.Bytes()))
+	ASSERT_EQ(mustString(s.JSONString()), `{"x":true}`, t)
+}
+
+func TestBuilderPool(t *testing.T) {
+	var p velocypack.BuilderPool
+	b := p.Get()
+	ASSERT_TRUE(b.IsEmpty(), t)
+	b.BuildUnindexedArrays = true
+	must(b.OpenArray())
+	must(b.AddValue(velocypack.NewIntValue(1)))
+	must(b.Close())
+	p.Put(b)
+
+	b = p.Get()
+	ASSERT_TRUE(b.IsEmpty(), t)
+	ASSERT_FALSE(b.BuildUnindexedArrays, t)
+	must(b.AddValue(velocypack.NewStringValue("foo")))
+	s := velocypack.Slice(mustBytes(b.Bytes()))
+	ASSERT_EQ(mustString(s.GetString()), "foo", t)
+	p.Put(b)
+}
+
+func TestBuilderPoolMaxCapacity(t *testing.T) {
+	p := velocypack.BuilderPool{MaxCapacity: 16}
+	b := p.Get()
+	must(b.AddValue(velocypack.NewStringValue("a string that is longer than the maximum capacity")))
+	p.Put(b)
+	// The large builder is dropped, so Get returns a builder with a small buffer.
+	b = p.Get()
+	ASSERT_TRUE(b.IsEmpty(), t)
+}
+
+func TestMarshalResultIsNotShared(t *testing.T) {
+	a := mustSlice(velocypack.Marshal(map[string]interface{}{"a": 1}))
+	b := mustSlice(velocypack.Marshal([]string{"b", "c"}))
+	ASSERT_EQ(mustString(a.JSONString()), `{"a":1}`, t)
+	ASSERT_EQ(mustString(b.JSONString()), `["b","c"]`, t)
+}
+
+func TestMarshalConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s, err := velocypack.Marshal([]int{i, j})
+				if err != nil {
+					t.Errorf("Marshal failed: %v", err)
+					return
+				}
+				if v := mustInt(mustSlice(s.At(1)).GetInt()); v != int64(j) {
+					t.Errorf("Expected %d, got %d", j, v)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
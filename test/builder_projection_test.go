@@ -0,0 +1,124 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+const projectionSource = `{"_key":"123","name":"John","age":42,"address":{"street":"Main","city":"Cologne","geo":{"lat":50.9,"lon":6.9}},"tags":["a","b"]}`
+
+func TestProject(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(projectionSource))
+	tests := []struct {
+		Projection velocypack.Projection
+		Expected   string
+	}{
+		{velocypack.Projection{}, `{"_key":"123","address":{"city":"Cologne","geo":{"lat":50.9,"lon":6.9},"street":"Main"},"age":42,"name":"John","tags":["a","b"]}`},
+		// KEEP
+		{velocypack.Projection{Include: [][]string{{"name"}, {"age"}}}, `{"age":42,"name":"John"}`},
+		{velocypack.Projection{Include: [][]string{{"name"}, {"notfound"}}}, `{"name":"John"}`},
+		// UNSET
+		{velocypack.Projection{Exclude: [][]string{{"_key"}, {"address"}, {"tags"}}}, `{"age":42,"name":"John"}`},
+		// Nested paths
+		{velocypack.Projection{Include: [][]string{{"name"}, {"address", "city"}, {"address", "geo", "lat"}}}, `{"address":{"city":"Cologne","geo":{"lat":50.9}},"name":"John"}`},
+		{velocypack.Projection{Exclude: [][]string{{"address", "geo"}, {"tags"}, {"_key"}}}, `{"address":{"city":"Cologne","street":"Main"},"age":42,"name":"John"}`},
+		{velocypack.Projection{Include: [][]string{{"address"}}, Exclude: [][]string{{"address", "street"}, {"address", "geo", "lon"}}}, `{"address":{"city":"Cologne","geo":{"lat":50.9}}}`},
+		// A shorter path selects the entire attribute
+		{velocypack.Projection{Include: [][]string{{"address", "city"}, {"address"}}}, `{"address":{"city":"Cologne","geo":{"lat":50.9,"lon":6.9},"street":"Main"}}`},
+		// Exclude takes precedence
+		{velocypack.Projection{Include: [][]string{{"name"}, {"age"}}, Exclude: [][]string{{"age"}}}, `{"name":"John"}`},
+		// Nested paths into values that are not objects
+		{velocypack.Projection{Include: [][]string{{"name", "first"}, {"age"}}}, `{"age":42}`},
+		{velocypack.Projection{Include: [][]string{{"name"}}, Exclude: [][]string{{"name", "first"}}}, `{"name":"John"}`},
+		// Rename
+		{velocypack.Projection{Include: [][]string{{"_key"}, {"address", "city"}}, Rename: map[string]string{"_key": "id", "address": "location"}}, `{"id":"123","location":{"city":"Cologne"}}`},
+	}
+	for _, test := range tests {
+		result := mustSlice(velocypack.Project(s, test.Projection))
+		ASSERT_EQ(mustString(result.JSONString()), test.Expected, t)
+	}
+}
+
+func TestProjectLarge(t *testing.T) {
+	const n = 5000
+	var b velocypack.Builder
+	must(b.OpenObject())
+	for i := 0; i < n; i++ {
+		must(b.AddKeyValue(fmt.Sprintf("k%d", i), velocypack.NewIntValue(int64(i))))
+	}
+	must(b.Close())
+	s := mustSlice(b.Slice())
+
+	result := mustSlice(velocypack.Project(s, velocypack.Projection{Exclude: [][]string{{"k0"}}}))
+	ASSERT_EQ(velocypack.ValueLength(n-1), mustLength(result.Length()), t)
+	ASSERT_EQ(int64(n-1), mustInt(mustSlice(result.Get(fmt.Sprintf("k%d", n-1))).GetInt()), t)
+
+	_, err := velocypack.Project(s, velocypack.Projection{Rename: map[string]string{"k0": fmt.Sprintf("k%d", n-1)}})
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsDuplicateAttributeName, t)(err)
+}
+
+func TestBuilderAddProjection(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(projectionSource))
+	var b velocypack.Builder
+	must(b.OpenObject())
+	must(b.AddKeyString("source", "users"))
+	must(b.AddProjection(s, velocypack.Projection{Include: [][]string{{"_key"}, {"name"}}}))
+	must(b.AddKeyBool("active", true))
+	must(b.Close())
+	ASSERT_EQ(mustString(mustSlice(b.Slice()).JSONString()), `{"_key":"123","active":true,"name":"John","source":"users"}`, t)
+}
+
+func TestBuilderAddProjectionDuplicate(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(projectionSource))
+	for _, indexKeys := range []bool{false, true} {
+		b := velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{IndexKeys: indexKeys}}
+		must(b.OpenObject())
+		must(b.AddKeyString("name", "Jane"))
+		// Existing key
+		ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsDuplicateAttributeName, t)(b.AddProjection(s, velocypack.Projection{Include: [][]string{{"address", "city"}, {"name"}}}))
+		// Renamed key that clashes with another copied key
+		ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsDuplicateAttributeName, t)(b.AddProjection(s, velocypack.Projection{Include: [][]string{{"age"}, {"_key"}}, Rename: map[string]string{"_key": "age"}}))
+		// The builder is unchanged after an error
+		must(b.Close())
+		ASSERT_EQ(mustString(mustSlice(b.Slice()).JSONString()), `{"name":"Jane"}`, t)
+	}
+}
+
+func TestBuilderAddProjectionErrors(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(projectionSource))
+	var b velocypack.Builder
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderNeedOpenObject, t)(b.AddProjection(s, velocypack.Projection{}))
+	must(b.OpenArray())
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderNeedOpenObject, t)(b.AddProjection(s, velocypack.Projection{}))
+	must(b.Close())
+
+	b.Clear()
+	must(b.OpenObject())
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsInvalidType, t)(b.AddProjection(mustSlice(velocypack.ParseJSONFromString(`[1,2]`)), velocypack.Projection{}))
+	must(b.AddValue(velocypack.NewStringValue("key")))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderKeyAlreadyWritten, t)(b.AddProjection(s, velocypack.Projection{}))
+}
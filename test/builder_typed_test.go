@@ -0,0 +1,149 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+func TestBuilderTypedArray(t *testing.T) {
+	date := time.Date(2017, 3, 4, 5, 6, 7, 0, time.UTC)
+	var b velocypack.Builder
+	must(b.OpenArray())
+	must(b.AddNull())
+	must(b.AddBool(true))
+	must(b.AddInt(-5))
+	must(b.AddInt(math.MinInt64))
+	must(b.AddUInt(math.MaxUint64))
+	must(b.AddDouble(1.5))
+	must(b.AddString("foo"))
+	must(b.AddBinary([]byte{1, 2, 3}))
+	must(b.AddUTCDate(date))
+	must(b.Close())
+
+	s := velocypack.Slice(mustBytes(b.Bytes()))
+	ASSERT_EQ(mustLength(s.Length()), velocypack.ValueLength(9), t)
+	ASSERT_TRUE(mustSlice(s.At(0)).IsNull(), t)
+	ASSERT_TRUE(mustBool(mustSlice(s.At(1)).GetBool()), t)
+	ASSERT_EQ(mustInt(mustSlice(s.At(2)).GetInt()), int64(-5), t)
+	ASSERT_EQ(mustInt(mustSlice(s.At(3)).GetInt()), int64(math.MinInt64), t)
+	ASSERT_EQ(mustUInt(mustSlice(s.At(4)).GetUInt()), uint64(math.MaxUint64), t)
+	ASSERT_DOUBLE_EQ(mustDouble(mustSlice(s.At(5)).GetDouble()), 1.5, t)
+	ASSERT_EQ(mustString(mustSlice(s.At(6)).GetString()), "foo", t)
+	ASSERT_EQ(mustBytes(mustSlice(s.At(7)).GetBinary()), []byte{1, 2, 3}, t)
+	ASSERT_TRUE(mustTime(mustSlice(s.At(8)).GetUTCDate()).Equal(date), t)
+}
+
+func TestBuilderTypedObject(t *testing.T) {
+	var b velocypack.Builder
+	must(b.OpenObject())
+	must(b.AddKeyNull("n"))
+	must(b.AddKeyBool("b", false))
+	must(b.AddKeyInt("i", 42))
+	must(b.AddKeyUInt("u", 7))
+	must(b.AddKeyDouble("d", 2.5))
+	must(b.AddKeyString("s", "bar"))
+	must(b.AddKeyBinary("bin", []byte{9}))
+	must(b.OpenArrayKey("arr"))
+	must(b.AddInt(1))
+	must(b.Close())
+	must(b.OpenObjectKey("obj"))
+	must(b.AddKeyString("x", "y"))
+	must(b.Close())
+	must(b.Close())
+
+	s := velocypack.Slice(mustBytes(b.Bytes()))
+	ASSERT_TRUE(mustSlice(s.Get("n")).IsNull(), t)
+	ASSERT_FALSE(mustBool(mustSlice(s.Get("b")).GetBool()), t)
+	ASSERT_EQ(mustInt(mustSlice(s.Get("i")).GetInt()), int64(42), t)
+	ASSERT_EQ(mustUInt(mustSlice(s.Get("u")).GetUInt()), uint64(7), t)
+	ASSERT_DOUBLE_EQ(mustDouble(mustSlice(s.Get("d")).GetDouble()), 2.5, t)
+	ASSERT_EQ(mustString(mustSlice(s.Get("s")).GetString()), "bar", t)
+	ASSERT_EQ(mustBytes(mustSlice(s.Get("bin")).GetBinary()), []byte{9}, t)
+	ASSERT_EQ(mustString(mustSlice(s.Get("arr")).JSONString()), "[1]", t)
+	ASSERT_EQ(mustString(mustSlice(s.Get("obj")).JSONString()), `{"x":"y"}`, t)
+}
+
+func TestBuilderTypedSameAsValue(t *testing.T) {
+	var typed, boxed velocypack.Builder
+	must(typed.OpenObject())
+	must(typed.AddKeyString("name", "John Doe"))
+	must(typed.AddKeyInt("age", 42))
+	must(typed.Close())
+	must(boxed.OpenObject())
+	must(boxed.AddKeyValue("name", velocypack.NewStringValue("John Doe")))
+	must(boxed.AddKeyValue("age", velocypack.NewIntValue(42)))
+	must(boxed.Close())
+	ASSERT_EQ(mustBytes(typed.Bytes()), mustBytes(boxed.Bytes()), t)
+}
+
+func TestBuilderTypedErrors(t *testing.T) {
+	var b velocypack.Builder
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderNeedOpenObject, t)(b.AddKeyInt("a", 1))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderNeedOpenObject, t)(b.OpenObjectKey("a"))
+
+	must(b.OpenArray())
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderNeedOpenObject, t)(b.AddKeyString("a", "b"))
+	must(b.Close())
+
+	var o velocypack.Builder
+	must(o.OpenObject())
+	// Keys must be strings.
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderKeyMustBeString, t)(o.AddInt(1))
+	must(o.AddString("key"))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderKeyAlreadyWritten, t)(o.AddKeyInt("other", 1))
+}
+
+func TestFluentBuilder(t *testing.T) {
+	var b velocypack.Builder
+	s, err := b.Fluent().
+		OpenObject().
+		AddKeyString("name", "John").
+		AddKeyInt("age", 42).
+		OpenArrayKey("tags").
+		AddString("a").
+		AddString("b").
+		Close().
+		Close().
+		Slice()
+	ASSERT_NIL(err, t)
+	ASSERT_EQ(mustString(s.JSONString()), `{"age":42,"name":"John","tags":["a","b"]}`, t)
+}
+
+func TestFluentBuilderError(t *testing.T) {
+	var b velocypack.Builder
+	f := b.Fluent()
+	f.OpenArray().
+		AddKeyInt("wrong", 1). // Not allowed in an array
+		AddInt(2).
+		Close()
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderNeedOpenObject, t)(f.Err())
+	_, err := f.Slice()
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderNeedOpenObject, t)(err)
+	// Calls after the error are ignored.
+	ASSERT_FALSE(f.Builder().IsClosed(), t)
+}
@@ -0,0 +1,106 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+func buildUnsortedObject(t *testing.T, keys []string, value func(i int) velocypack.Value) velocypack.Slice {
+	b := velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{BuildUnsortedObjects: true}}
+	must(b.OpenObject())
+	for i, k := range keys {
+		must(b.AddKeyValue(k, value(i)))
+	}
+	must(b.Close())
+	return mustSlice(b.Slice())
+}
+
+func TestBuilderUnsortedObject(t *testing.T) {
+	keys := []string{"z", "b", "y", "a", "x", "c"}
+	s := buildUnsortedObject(t, keys, func(i int) velocypack.Value { return velocypack.NewIntValue(int64(i)) })
+
+	ASSERT_EQ(s.Type(), velocypack.Object, t)
+	ASSERT_EQ(s[0], byte(0x0f), t)
+	ASSERT_FALSE(s.IsSorted(), t)
+	ASSERT_EQ(mustLength(s.Length()), velocypack.ValueLength(len(keys)), t)
+
+	// Attributes keep the order in which they were added.
+	ASSERT_EQ(mustString(s.JSONString()), `{"z":0,"b":1,"y":2,"a":3,"x":4,"c":5}`, t)
+
+	// Lookups fall back to a linear search.
+	for i, k := range keys {
+		ASSERT_EQ(mustInt(mustSlice(s.Get(k)).GetInt()), int64(i), t)
+	}
+	ASSERT_TRUE(mustSlice(s.Get("notfound")).IsNone(), t)
+}
+
+func TestBuilderUnsortedObjectLarge(t *testing.T) {
+	var keys []string
+	for i := 100; i > 0; i-- {
+		keys = append(keys, fmt.Sprintf("key%03d", i))
+	}
+	s := buildUnsortedObject(t, keys, func(i int) velocypack.Value {
+		return velocypack.NewStringValue(strings.Repeat("v", i))
+	})
+
+	ASSERT_EQ(s[0], byte(0x10), t)
+	for i, k := range keys {
+		ASSERT_EQ(mustString(mustSlice(s.Get(k)).GetString()), strings.Repeat("v", i), t)
+	}
+	ASSERT_EQ(mustLength(s.ByteSize()), velocypack.ValueLength(len(s)), t)
+
+	// Round trip through a reader.
+	r, err := velocypack.SliceFromReader(bytes.NewReader(s))
+	ASSERT_NIL(err, t)
+	ASSERT_EQ(r, s, t)
+}
+
+func TestBuilderUnsortedObjectUnmarshal(t *testing.T) {
+	s := buildUnsortedObject(t, []string{"name", "age", "city", "country"}, func(i int) velocypack.Value {
+		return velocypack.NewStringValue(fmt.Sprintf("v%d", i))
+	})
+	var v map[string]string
+	ASSERT_NIL(velocypack.Unmarshal(s, &v), t)
+	ASSERT_EQ(v, map[string]string{"name": "v0", "age": "v1", "city": "v2", "country": "v3"}, t)
+}
+
+func TestBuilderUnsortedObjectUniqueness(t *testing.T) {
+	b := velocypack.Builder{BuilderOptions: velocypack.BuilderOptions{BuildUnsortedObjects: true, CheckAttributeUniqueness: true}}
+	must(b.OpenObject())
+	must(b.AddKeyValue("b", velocypack.NewIntValue(1)))
+	must(b.AddKeyValue("a", velocypack.NewIntValue(2)))
+	must(b.AddKeyValue("b", velocypack.NewIntValue(3)))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsDuplicateAttributeName, t)(b.Close())
+}
+
+func TestBuilderUnsortedObjectSingle(t *testing.T) {
+	// Objects with a single attribute use the compact format.
+	s := buildUnsortedObject(t, []string{"a"}, func(i int) velocypack.Value { return velocypack.NewBoolValue(true) })
+	ASSERT_TRUE(mustBool(mustSlice(s.Get("a")).GetBool()), t)
+}
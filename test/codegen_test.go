@@ -0,0 +1,136 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+// codegenPersonMirror has the same layout as CodegenPerson, but no generated
+// methods, so it is encoded using reflection.
+type codegenPersonMirror struct {
+	Name    string            `json:"name"`
+	Age     int               `json:"age"`
+	Score   float64           `json:"score,omitempty"`
+	ID      uint64            `json:"id"`
+	Active  bool              `json:"active"`
+	Avatar  []byte            `json:"avatar"`
+	Tags    []string          `json:"tags,omitempty"`
+	Address *CodegenAddress   `json:"address,omitempty"`
+	Home    CodegenAddress    `json:"home"`
+	Extra   map[string]string `json:"extra,omitempty"`
+}
+
+func TestCodegenMarshal(t *testing.T) {
+	tests := []CodegenPerson{
+		{},
+		{Name: "Jan", Age: 42, Score: 3.5, ID: 1 << 60, Active: true},
+		{Name: "Piet", Avatar: []byte{1, 2, 3}, Tags: []string{"a", "b"}},
+		{Address: &CodegenAddress{Street: "Main", Number: 12}, Extra: map[string]string{"x": "y"}},
+		{Home: CodegenAddress{Street: "Side", Number: 3}},
+	}
+	for _, test := range tests {
+		generated, err := velocypack.Marshal(test)
+		ASSERT_NIL(err, t)
+		reflected, err := velocypack.Marshal(codegenPersonMirror{
+			Name:    test.Name,
+			Age:     test.Age,
+			Score:   test.Score,
+			ID:      test.ID,
+			Active:  test.Active,
+			Avatar:  test.Avatar,
+			Tags:    test.Tags,
+			Address: test.Address,
+			Home:    test.Home,
+			Extra:   test.Extra,
+		})
+		ASSERT_NIL(err, t)
+		ASSERT_EQ(mustString(reflected.JSONString()), mustString(generated.JSONString()), t)
+	}
+}
+
+func TestCodegenMarshalVPackTo(t *testing.T) {
+	p := CodegenPerson{Name: "Jan", Address: &CodegenAddress{Street: "Main", Number: 12}}
+	expected, err := velocypack.Marshal(p)
+	ASSERT_NIL(err, t)
+
+	// The generated value is added directly to the parent builder.
+	var b velocypack.Builder
+	must(b.OpenArray())
+	must(p.MarshalVPackTo(&b))
+	must(b.Close())
+	ASSERT_EQ(mustSlice(mustSlice(b.Slice()).At(0)), expected, t)
+}
+
+func TestCodegenMarshalIgnoredFields(t *testing.T) {
+	s, err := velocypack.Marshal(CodegenPerson{Ignored: "foo", internal: 7})
+	ASSERT_NIL(err, t)
+	ASSERT_FALSE(mustBool(s.HasKey("Ignored")), t)
+	ASSERT_FALSE(mustBool(s.HasKey("internal")), t)
+}
+
+func TestCodegenUnmarshal(t *testing.T) {
+	expected := CodegenPerson{
+		Name:    "Jan",
+		Age:     -42,
+		Score:   1.25,
+		ID:      1 << 63,
+		Active:  true,
+		Avatar:  []byte{0xff, 0x00},
+		Tags:    []string{"x", "y", "z"},
+		Address: &CodegenAddress{Street: "Main", Number: 7},
+		Home:    CodegenAddress{Street: "Side"},
+		Extra:   map[string]string{"a": "b"},
+	}
+	s, err := velocypack.Marshal(expected)
+	ASSERT_NIL(err, t)
+
+	var v CodegenPerson
+	ASSERT_NIL(velocypack.Unmarshal(s, &v), t)
+	ASSERT_TRUE(reflect.DeepEqual(expected, v), t)
+}
+
+func TestCodegenUnmarshalConvert(t *testing.T) {
+	// Values that do not match the fast path are decoded using reflection.
+	s := mustSlice(velocypack.ParseJSONFromString(`{"age":12.0,"score":7,"unknown":true,"address":null}`))
+	var v CodegenPerson
+	ASSERT_NIL(velocypack.Unmarshal(s, &v), t)
+	ASSERT_EQ(12, v.Age, t)
+	ASSERT_EQ(7.0, v.Score, t)
+	ASSERT_TRUE(v.Address == nil, t)
+}
+
+func TestCodegenUnmarshalOutOfRange(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(`{"street":"Main","number":70000}`))
+	var v CodegenAddress
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsNumberOutOfRange, t)(velocypack.Unmarshal(s, &v))
+}
+
+func TestCodegenUnmarshalNotObject(t *testing.T) {
+	var v CodegenAddress
+	s := mustSlice(velocypack.ParseJSONFromString(`5`))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsInvalidType, t)(velocypack.Unmarshal(s, &v))
+}
@@ -0,0 +1,45 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+//go:generate go run ../tools/vpackgen/main.go -type=CodegenAddress,CodegenPerson
+
+type CodegenAddress struct {
+	Street string `json:"street"`
+	Number int16  `json:"number,omitempty"`
+}
+
+type CodegenPerson struct {
+	Name     string            `json:"name"`
+	Age      int               `json:"age"`
+	Score    float64           `json:"score,omitempty"`
+	ID       uint64            `json:"id"`
+	Active   bool              `json:"active"`
+	Avatar   []byte            `json:"avatar"`
+	Tags     []string          `json:"tags,omitempty"`
+	Address  *CodegenAddress   `json:"address,omitempty"`
+	Home     CodegenAddress    `json:"home"`
+	Extra    map[string]string `json:"extra,omitempty"`
+	Ignored  string            `json:"-"`
+	internal int
+}
@@ -0,0 +1,265 @@
+// Code generated by vpackgen. DO NOT EDIT.
+
+package test
+
+import velocypack "github.com/arangodb/go-velocypack"
+
+// MarshalVPack implements velocypack.Marshaler.
+func (v CodegenAddress) MarshalVPack() (velocypack.Slice, error) {
+	var b velocypack.Builder
+	if err := v.MarshalVPackTo(&b); err != nil {
+		return nil, err
+	}
+	return b.Slice()
+}
+
+// MarshalVPackTo implements velocypack.BuilderMarshaler.
+func (v CodegenAddress) MarshalVPackTo(b *velocypack.Builder) error {
+	if err := b.OpenObject(); err != nil {
+		return err
+	}
+	if err := b.AddKeyValue("street", velocypack.NewStringValue(v.Street)); err != nil {
+		return err
+	}
+	if v.Number != 0 {
+		if err := b.AddKeyValue("number", velocypack.NewIntValue(int64(v.Number))); err != nil {
+			return err
+		}
+	}
+	return b.Close()
+}
+
+// UnmarshalVPack implements velocypack.Unmarshaler.
+func (v *CodegenAddress) UnmarshalVPack(s velocypack.Slice) error {
+	if s.IsNull() {
+		return nil
+	}
+	it, err := velocypack.NewObjectIterator(s, true)
+	if err != nil {
+		return err
+	}
+	for it.IsValid() {
+		k, err := it.Key(true)
+		if err != nil {
+			return err
+		}
+		key, err := k.GetStringUTF8()
+		if err != nil {
+			return err
+		}
+		value, err := it.Value()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "street":
+			if value.IsString() {
+				x, err := value.GetString()
+				if err != nil {
+					return err
+				}
+				v.Street = x
+			} else if err := velocypack.Unmarshal(value, &v.Street); err != nil {
+				return err
+			}
+		case "number":
+			if value.IsInteger() {
+				x, err := value.GetInt()
+				if err != nil {
+					return err
+				}
+				if int64(int16(x)) != x {
+					return velocypack.NumberOutOfRangeError
+				}
+				v.Number = int16(x)
+			} else if err := velocypack.Unmarshal(value, &v.Number); err != nil {
+				return err
+			}
+		}
+		if err := it.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalVPack implements velocypack.Marshaler.
+func (v CodegenPerson) MarshalVPack() (velocypack.Slice, error) {
+	var b velocypack.Builder
+	if err := v.MarshalVPackTo(&b); err != nil {
+		return nil, err
+	}
+	return b.Slice()
+}
+
+// MarshalVPackTo implements velocypack.BuilderMarshaler.
+func (v CodegenPerson) MarshalVPackTo(b *velocypack.Builder) error {
+	if err := b.OpenObject(); err != nil {
+		return err
+	}
+	if err := b.AddKeyValue("name", velocypack.NewStringValue(v.Name)); err != nil {
+		return err
+	}
+	if err := b.AddKeyValue("age", velocypack.NewIntValue(int64(v.Age))); err != nil {
+		return err
+	}
+	if v.Score != 0 {
+		if err := b.AddKeyValue("score", velocypack.NewDoubleValue(v.Score)); err != nil {
+			return err
+		}
+	}
+	if err := b.AddKeyValue("id", velocypack.NewUIntValue(v.ID)); err != nil {
+		return err
+	}
+	if err := b.AddKeyValue("active", velocypack.NewBoolValue(v.Active)); err != nil {
+		return err
+	}
+	if v.Avatar == nil {
+		if err := b.AddKeyValue("avatar", velocypack.NewNullValue()); err != nil {
+			return err
+		}
+	} else {
+		if err := b.AddKeyValue("avatar", velocypack.NewBinaryValue(v.Avatar)); err != nil {
+			return err
+		}
+	}
+	if len(v.Tags) != 0 {
+		if s, err := velocypack.Marshal(v.Tags); err != nil {
+			return err
+		} else if err := b.AddKeyValue("tags", velocypack.NewSliceValue(s)); err != nil {
+			return err
+		}
+	}
+	if v.Address != nil {
+		if err := b.AddValue(velocypack.NewStringValue("address")); err != nil {
+			return err
+		}
+		if err := v.Address.MarshalVPackTo(b); err != nil {
+			return err
+		}
+	}
+	if err := b.AddValue(velocypack.NewStringValue("home")); err != nil {
+		return err
+	}
+	if err := v.Home.MarshalVPackTo(b); err != nil {
+		return err
+	}
+	if len(v.Extra) != 0 {
+		if s, err := velocypack.Marshal(v.Extra); err != nil {
+			return err
+		} else if err := b.AddKeyValue("extra", velocypack.NewSliceValue(s)); err != nil {
+			return err
+		}
+	}
+	return b.Close()
+}
+
+// UnmarshalVPack implements velocypack.Unmarshaler.
+func (v *CodegenPerson) UnmarshalVPack(s velocypack.Slice) error {
+	if s.IsNull() {
+		return nil
+	}
+	it, err := velocypack.NewObjectIterator(s, true)
+	if err != nil {
+		return err
+	}
+	for it.IsValid() {
+		k, err := it.Key(true)
+		if err != nil {
+			return err
+		}
+		key, err := k.GetStringUTF8()
+		if err != nil {
+			return err
+		}
+		value, err := it.Value()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "name":
+			if value.IsString() {
+				x, err := value.GetString()
+				if err != nil {
+					return err
+				}
+				v.Name = x
+			} else if err := velocypack.Unmarshal(value, &v.Name); err != nil {
+				return err
+			}
+		case "age":
+			if value.IsInteger() {
+				x, err := value.GetInt()
+				if err != nil {
+					return err
+				}
+				if int64(int(x)) != x {
+					return velocypack.NumberOutOfRangeError
+				}
+				v.Age = int(x)
+			} else if err := velocypack.Unmarshal(value, &v.Age); err != nil {
+				return err
+			}
+		case "score":
+			if value.IsDouble() {
+				x, err := value.GetDouble()
+				if err != nil {
+					return err
+				}
+				v.Score = x
+			} else if err := velocypack.Unmarshal(value, &v.Score); err != nil {
+				return err
+			}
+		case "id":
+			if value.IsInteger() {
+				x, err := value.GetUInt()
+				if err != nil {
+					return err
+				}
+				v.ID = x
+			} else if err := velocypack.Unmarshal(value, &v.ID); err != nil {
+				return err
+			}
+		case "active":
+			if value.IsBool() {
+				x, err := value.GetBool()
+				if err != nil {
+					return err
+				}
+				v.Active = x
+			} else if err := velocypack.Unmarshal(value, &v.Active); err != nil {
+				return err
+			}
+		case "avatar":
+			if value.IsBinary() {
+				x, err := value.GetBinary()
+				if err != nil {
+					return err
+				}
+				v.Avatar = append([]byte{}, x...)
+			} else if err := velocypack.Unmarshal(value, &v.Avatar); err != nil {
+				return err
+			}
+		case "tags":
+			if err := velocypack.Unmarshal(value, &v.Tags); err != nil {
+				return err
+			}
+		case "address":
+			if err := velocypack.Unmarshal(value, &v.Address); err != nil {
+				return err
+			}
+		case "home":
+			if err := velocypack.Unmarshal(value, &v.Home); err != nil {
+				return err
+			}
+		case "extra":
+			if err := velocypack.Unmarshal(value, &v.Extra); err != nil {
+				return err
+			}
+		}
+		if err := it.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
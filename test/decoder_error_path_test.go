@@ -0,0 +1,124 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+type ErrorPathAddress struct {
+	Street string `json:"street"`
+	Zip    int    `json:"zip"`
+}
+
+type ErrorPathUser struct {
+	Name    string           `json:"name"`
+	Age     int              `json:"age"`
+	Address ErrorPathAddress `json:"address"`
+}
+
+type ErrorPathDoc struct {
+	Users []ErrorPathUser `json:"users"`
+}
+
+func errorPathInput(t *testing.T) velocypack.Slice {
+	s, err := velocypack.ParseJSONFromString(`{"users":[` +
+		`{"name":"a","age":1,"address":{"street":"s","zip":1234}},` +
+		`{"name":"b","age":"old","address":{"street":"s","zip":"1234AB"}},` +
+		`{"name":true,"age":3,"address":{"street":"s","zip":5678}}` +
+		`]}`)
+	ASSERT_NIL(err, t)
+	return s
+}
+
+func TestDecoderErrorPath(t *testing.T) {
+	s := errorPathInput(t)
+	var v ErrorPathDoc
+	err := velocypack.Unmarshal(s, &v)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnmarshalType, t)(err)
+
+	te, ok := velocypack.Cause(err).(*velocypack.UnmarshalTypeError)
+	ASSERT_TRUE(ok, t)
+	ASSERT_EQ(te.Path, "users[1].address.zip", t)
+	ASSERT_EQ(te.Found, velocypack.String, t)
+	ASSERT_EQ(te.Struct, "ErrorPathAddress", t)
+	ASSERT_EQ(te.Field, "zip", t)
+
+	// The offset must point at the offending value.
+	ASSERT_TRUE(te.Offset > 0, t)
+	ASSERT_EQ(mustString(s[te.Offset:].GetString()), "1234AB", t)
+
+	// Other values are still decoded.
+	ASSERT_EQ(len(v.Users), 3, t)
+	ASSERT_EQ(v.Users[2].Address.Zip, 5678, t)
+}
+
+func TestDecoderAllErrors(t *testing.T) {
+	s := errorPathInput(t)
+	var v ErrorPathDoc
+	err := velocypack.Unmarshal(s, &v, velocypack.DecoderOptions{AllErrors: true})
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnmarshalType, t)(err)
+
+	errs, ok := velocypack.Cause(err).(velocypack.UnmarshalTypeErrors)
+	ASSERT_TRUE(ok, t)
+	ASSERT_EQ(len(errs), 3, t)
+	ASSERT_EQ(errs[0].Path, "users[1].address.zip", t)
+	ASSERT_EQ(errs[1].Path, "users[1].age", t)
+	ASSERT_EQ(errs[1].Found, velocypack.String, t)
+	ASSERT_EQ(mustString(s[errs[1].Offset:].GetString()), "old", t)
+	ASSERT_EQ(errs[2].Path, "users[2].name", t)
+	ASSERT_EQ(errs[2].Found, velocypack.Bool, t)
+}
+
+func TestDecoderAllErrorsNone(t *testing.T) {
+	s := velocypack.Slice(mustBytes(velocypack.Marshal(ErrorPathUser{Name: "a", Age: 7})))
+	var v ErrorPathUser
+	ASSERT_NIL(velocypack.Unmarshal(s, &v, velocypack.DecoderOptions{AllErrors: true}), t)
+	ASSERT_EQ(v.Age, 7, t)
+}
+
+func TestDecoderErrorPathTopLevel(t *testing.T) {
+	s := velocypack.Slice(mustBytes(velocypack.Marshal("foo")))
+	var v int
+	err := velocypack.Unmarshal(s, &v)
+	te, ok := velocypack.Cause(err).(*velocypack.UnmarshalTypeError)
+	ASSERT_TRUE(ok, t)
+	ASSERT_EQ(te.Path, "", t)
+	ASSERT_EQ(te.Found, velocypack.String, t)
+	ASSERT_EQ(te.Offset, int64(0), t)
+}
+
+func TestDecoderErrorPathMapPairs(t *testing.T) {
+	s, err := velocypack.ParseJSONFromString(`{"m":[[1,"a"],[2,true]]}`)
+	ASSERT_NIL(err, t)
+	var v struct {
+		M map[int]string `json:"m"`
+	}
+	err = velocypack.Unmarshal(s, &v)
+	te, ok := velocypack.Cause(err).(*velocypack.UnmarshalTypeError)
+	ASSERT_TRUE(ok, t)
+	ASSERT_EQ(te.Path, "m[1][1]", t)
+	ASSERT_EQ(te.Found, velocypack.Bool, t)
+}
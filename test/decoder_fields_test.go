@@ -0,0 +1,100 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+type fieldsAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type fieldsPerson struct {
+	Name      string          `json:"name"`
+	Age       int             `json:"age"`
+	Address   fieldsAddress   `json:"address"`
+	Previous  []fieldsAddress `json:"previous"`
+	Unchanged string          `json:"unchanged"`
+}
+
+const fieldsInput = `{"name":"Jan","age":42,"unchanged":"new","address":{"street":"Main","city":"Cologne"},"previous":[{"street":"A","city":"X"},{"street":"B","city":"Y"}]}`
+
+func TestUnmarshalFieldsTopLevel(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(fieldsInput))
+	v := fieldsPerson{Name: "old", Unchanged: "old"}
+	must(velocypack.UnmarshalFields(s, &v, "age", "address"))
+	ASSERT_EQ(v, fieldsPerson{
+		Name:      "old",
+		Age:       42,
+		Address:   fieldsAddress{Street: "Main", City: "Cologne"},
+		Unchanged: "old",
+	}, t)
+}
+
+func TestUnmarshalFieldsNested(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(fieldsInput))
+	v := fieldsPerson{Address: fieldsAddress{Street: "old", City: "old"}}
+	must(velocypack.UnmarshalFields(s, &v, "address.city", "previous.street"))
+	ASSERT_EQ(v.Address, fieldsAddress{Street: "old", City: "Cologne"}, t)
+	ASSERT_EQ(v.Previous, []fieldsAddress{{Street: "A"}, {Street: "B"}}, t)
+	ASSERT_EQ(v.Name, "", t)
+}
+
+func TestUnmarshalFieldsOverlapping(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(fieldsInput))
+	var v fieldsPerson
+	must(velocypack.UnmarshalFields(s, &v, "address.city", "address"))
+	ASSERT_EQ(v.Address, fieldsAddress{Street: "Main", City: "Cologne"}, t)
+}
+
+func TestUnmarshalFieldsMap(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(fieldsInput))
+	var v map[string]interface{}
+	must(velocypack.UnmarshalFields(s, &v, "name", "address.street"))
+	ASSERT_EQ(v, map[string]interface{}{
+		"name":    "Jan",
+		"address": map[string]interface{}{"street": "Main"},
+	}, t)
+}
+
+func TestUnmarshalFieldsSkipsInvalid(t *testing.T) {
+	// Attributes that are not selected are not decoded, so type errors cannot occur.
+	s := mustSlice(velocypack.ParseJSONFromString(`{"name":"Jan","age":"not a number"}`))
+	var v fieldsPerson
+	must(velocypack.UnmarshalFields(s, &v, "name"))
+	ASSERT_EQ(v.Name, "Jan", t)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnmarshalType, t)(velocypack.UnmarshalFields(s, &v, "name", "age"))
+}
+
+func TestUnmarshalFieldsNoPaths(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(fieldsInput))
+	var v1, v2 fieldsPerson
+	must(velocypack.UnmarshalFields(s, &v1))
+	must(velocypack.Unmarshal(s, &v2))
+	ASSERT_EQ(v1.Address, v2.Address, t)
+	ASSERT_EQ(v1.Name, v2.Name, t)
+}
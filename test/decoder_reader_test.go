@@ -24,6 +24,8 @@ package test
 
 import (
 	"bytes"
+	"io"
+	"io/ioutil"
 	"testing"
 
 	velocypack "github.com/arangodb/go-velocypack"
@@ -87,3 +89,68 @@ func TestDecoderReaderStruct1(t *testing.T) {
 		ASSERT_EQ(v, expected, t)
 	}
 }
+
+func TestDecoderReaderMore(t *testing.T) {
+	var s velocypack.Slice
+	for i := 0; i < 10; i++ {
+		s = append(s, mustSlice(velocypack.Marshal(i))...)
+	}
+	d := velocypack.NewDecoder(bytes.NewReader(s))
+
+	count := 0
+	for d.More() {
+		var v int
+		must(d.Decode(&v))
+		ASSERT_EQ(v, count, t)
+		count++
+	}
+	ASSERT_EQ(count, 10, t)
+}
+
+func TestDecoderReaderToken(t *testing.T) {
+	s1 := mustSlice(velocypack.Marshal(Struct1{Field1: 7}))
+	s2 := mustSlice(velocypack.Marshal("foo"))
+	d := velocypack.NewDecoder(bytes.NewReader(append(append(velocypack.Slice{}, s1...), s2...)))
+
+	ASSERT_EQ(mustSlice(d.Token()), s1, t)
+	var v string
+	must(d.Decode(&v))
+	ASSERT_EQ(v, "foo", t)
+
+	_, err := d.Token()
+	ASSERT_EQ(err, io.EOF, t)
+}
+
+func TestDecoderReaderBuffered(t *testing.T) {
+	s := append(velocypack.NullSlice(), velocypack.TrueSlice()...)
+	d := velocypack.NewDecoder(bytes.NewReader(s))
+
+	var v interface{}
+	must(d.Decode(&v))
+	rest, err := ioutil.ReadAll(d.Buffered())
+	ASSERT_NIL(err, t)
+	ASSERT_EQ(velocypack.Slice(rest), velocypack.TrueSlice(), t)
+}
+
+func TestDecoderReaderMaxSize(t *testing.T) {
+	small := mustSlice(velocypack.Marshal("foo"))
+	large := mustSlice(velocypack.Marshal(make([]byte, 1024)))
+	d := velocypack.NewDecoder(bytes.NewReader(append(append(velocypack.Slice{}, small...), large...)))
+	d.SetMaxSize(128)
+
+	var v string
+	must(d.Decode(&v))
+	ASSERT_EQ(v, "foo", t)
+
+	var b []byte
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsSliceTooLarge, t)(d.Decode(&b))
+}
+
+func TestDecoderReaderMaxSizeHeader(t *testing.T) {
+	// Long string header claiming a huge length, without any data.
+	s := velocypack.Slice{0xbf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x0f}
+	d := velocypack.NewDecoder(bytes.NewReader(s))
+	d.SetMaxSize(1 << 20)
+
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsSliceTooLarge, t)(d.Token())
+}
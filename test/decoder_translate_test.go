@@ -0,0 +1,119 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+// intKeyObject returns an object with integer keys 1 (true), 6 (false) and 300 (null).
+func intKeyObject() velocypack.Slice {
+	s := velocypack.Slice{0x0b,
+		0x00,             // Bytesize
+		0x03,             // NoItems
+		0x28, 0x01, 0x1a, // 1: true
+		0x28, 0x06, 0x19, // 6: false
+		0x29, 0x2c, 0x01, 0x18, // 300: null
+		3, 6, 9, // Index
+	}
+	s[1] = byte(len(s))
+	return s
+}
+
+type testTranslator map[uint64]string
+
+func (t testTranslator) IDToString(id uint64) string {
+	return t[id]
+}
+
+func TestDecoderTranslateDefault(t *testing.T) {
+	var v map[string]interface{}
+	must(velocypack.Unmarshal(intKeyObject(), &v))
+	ASSERT_EQ(v, map[string]interface{}{"_key": true, "6": false, "300": nil}, t)
+}
+
+func TestDecoderTranslateCustom(t *testing.T) {
+	opts := velocypack.DecoderOptions{
+		AttributeTranslator: testTranslator{1: "one", 6: "six", 300: "many"},
+	}
+	var v map[string]bool
+	must(velocypack.Unmarshal(intKeyObject(), &v, opts))
+	ASSERT_EQ(v, map[string]bool{"one": true, "six": false, "many": false}, t)
+
+	var i interface{}
+	must(velocypack.Unmarshal(intKeyObject(), &i, opts))
+	ASSERT_EQ(i, map[string]interface{}{"one": true, "six": false, "many": nil}, t)
+}
+
+func TestDecoderTranslateStruct(t *testing.T) {
+	var v struct {
+		Key   bool `json:"_key"`
+		Other bool `json:"six"`
+	}
+	opts := velocypack.DecoderOptions{
+		AttributeTranslator: testTranslator{6: "six"},
+	}
+	v.Other = true
+	must(velocypack.Unmarshal(intKeyObject(), &v, opts))
+	// ID 1 is unknown to the custom translator, so it does not map to _key.
+	ASSERT_FALSE(v.Key, t)
+	ASSERT_FALSE(v.Other, t)
+}
+
+func TestDecoderTranslateDisallowUnknown(t *testing.T) {
+	opts := velocypack.DecoderOptions{
+		DisallowUnknownAttributeIDs: true,
+	}
+	var v map[string]interface{}
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnknownAttributeID, t)(velocypack.Unmarshal(intKeyObject(), &v, opts))
+
+	opts.AttributeTranslator = testTranslator{1: "a", 6: "b", 300: "c"}
+	var v2 map[string]interface{}
+	must(velocypack.Unmarshal(intKeyObject(), &v2, opts))
+	ASSERT_EQ(v2, map[string]interface{}{"a": true, "b": false, "c": nil}, t)
+}
+
+func TestDecoderIntegerKeys(t *testing.T) {
+	opts := velocypack.DecoderOptions{
+		DisallowUnknownAttributeIDs: true,
+	}
+	var v map[uint64]interface{}
+	must(velocypack.Unmarshal(intKeyObject(), &v, opts))
+	ASSERT_EQ(v, map[uint64]interface{}{1: true, 6: false, 300: nil}, t)
+
+	var small map[int8]bool
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnmarshalType, t)(velocypack.Unmarshal(intKeyObject(), &small))
+}
+
+func TestDecoderReaderTranslate(t *testing.T) {
+	opts := velocypack.DecoderOptions{
+		AttributeTranslator: testTranslator{1: "one", 6: "six", 300: "many"},
+	}
+	d := velocypack.NewDecoder(bytes.NewReader(intKeyObject()), opts)
+	var v map[string]bool
+	must(d.Decode(&v))
+	ASSERT_EQ(v, map[string]bool{"one": true, "six": false, "many": false}, t)
+}
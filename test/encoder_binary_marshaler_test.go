@@ -0,0 +1,185 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+// BinaryID implements only encoding.BinaryMarshaler/BinaryUnmarshaler.
+type BinaryID struct {
+	Hi, Lo byte
+}
+
+func (id BinaryID) MarshalBinary() ([]byte, error) {
+	return []byte{id.Hi, id.Lo}, nil
+}
+
+func (id *BinaryID) UnmarshalBinary(data []byte) error {
+	if len(data) != 2 {
+		return errors.New("invalid length")
+	}
+	id.Hi, id.Lo = data[0], data[1]
+	return nil
+}
+
+// BinaryTextAddr implements both the text and binary marshaling interfaces.
+type BinaryTextAddr struct {
+	A, B byte
+}
+
+func (a BinaryTextAddr) MarshalText() ([]byte, error) {
+	return []byte{'0' + a.A, '.', '0' + a.B}, nil
+}
+
+func (a *BinaryTextAddr) UnmarshalText(text []byte) error {
+	if len(text) != 3 {
+		return errors.New("invalid text")
+	}
+	a.A, a.B = text[0]-'0', text[2]-'0'
+	return nil
+}
+
+func (a BinaryTextAddr) MarshalBinary() ([]byte, error) {
+	return []byte{a.A, a.B}, nil
+}
+
+func (a *BinaryTextAddr) UnmarshalBinary(data []byte) error {
+	if len(data) != 2 {
+		return errors.New("invalid length")
+	}
+	a.A, a.B = data[0], data[1]
+	return nil
+}
+
+func TestEncoderBinaryMarshaler(t *testing.T) {
+	bytes, err := velocypack.Marshal(BinaryID{1, 2})
+	ASSERT_NIL(err, t)
+	s := velocypack.Slice(bytes)
+
+	ASSERT_EQ(s.Type(), velocypack.Binary, t)
+	ASSERT_EQ(mustBytes(s.GetBinary()), []byte{1, 2}, t)
+
+	var v BinaryID
+	ASSERT_NIL(velocypack.Unmarshal(s, &v), t)
+	ASSERT_EQ(v, BinaryID{1, 2}, t)
+}
+
+func TestEncoderBinaryMarshalerStruct(t *testing.T) {
+	type Struct struct {
+		ID  BinaryID
+		Ptr *BinaryID
+		Nil *BinaryID
+	}
+	bytes, err := velocypack.Marshal(Struct{ID: BinaryID{3, 4}, Ptr: &BinaryID{5, 6}})
+	ASSERT_NIL(err, t)
+	s := velocypack.Slice(bytes)
+
+	ASSERT_EQ(mustSlice(s.Get("ID")).Type(), velocypack.Binary, t)
+	ASSERT_TRUE(mustSlice(s.Get("Nil")).IsNull(), t)
+
+	var v Struct
+	ASSERT_NIL(velocypack.Unmarshal(s, &v), t)
+	ASSERT_EQ(v.ID, BinaryID{3, 4}, t)
+	ASSERT_EQ(*v.Ptr, BinaryID{5, 6}, t)
+	ASSERT_TRUE(v.Nil == nil, t)
+}
+
+func TestEncoderBinaryMarshalerURL(t *testing.T) {
+	u, err := url.Parse("https://example.com/path?q=1")
+	ASSERT_NIL(err, t)
+	bytes, err := velocypack.Marshal(u)
+	ASSERT_NIL(err, t)
+	s := velocypack.Slice(bytes)
+	ASSERT_EQ(s.Type(), velocypack.Binary, t)
+
+	var v *url.URL
+	ASSERT_NIL(velocypack.Unmarshal(s, &v), t)
+	ASSERT_EQ(v.String(), u.String(), t)
+}
+
+func TestEncoderBinaryMarshalerBinaryByDefault(t *testing.T) {
+	bytes, err := velocypack.Marshal([]BinaryTextAddr{{1, 2}, {3, 4}})
+	ASSERT_NIL(err, t)
+	s := velocypack.Slice(bytes)
+
+	ASSERT_EQ(mustSlice(s.At(0)).Type(), velocypack.Binary, t)
+	ASSERT_EQ(mustBytes(mustSlice(s.At(1)).GetBinary()), []byte{3, 4}, t)
+
+	var v []BinaryTextAddr
+	ASSERT_NIL(velocypack.Unmarshal(s, &v), t)
+	ASSERT_EQ(v, []BinaryTextAddr{{1, 2}, {3, 4}}, t)
+}
+
+func TestEncoderPreferTextMarshaler(t *testing.T) {
+	opts := velocypack.EncoderOptions{PreferTextMarshaler: true}
+	bytes, err := velocypack.Marshal(BinaryTextAddr{1, 2}, opts)
+	ASSERT_NIL(err, t)
+	s := velocypack.Slice(bytes)
+
+	ASSERT_EQ(s.Type(), velocypack.String, t)
+	ASSERT_EQ(mustString(s.GetString()), "1.2", t)
+
+	var v BinaryTextAddr
+	ASSERT_NIL(velocypack.Unmarshal(s, &v), t)
+	ASSERT_EQ(v, BinaryTextAddr{1, 2}, t)
+
+	// Values implementing only BinaryMarshaler are still encoded as Binary.
+	s = mustSlice(velocypack.Marshal(BinaryID{1, 2}, opts))
+	ASSERT_EQ(s.Type(), velocypack.Binary, t)
+}
+
+func TestEncoderBinaryMarshalerURLField(t *testing.T) {
+	// url.URL implements only BinaryMarshaler, with a pointer receiver.
+	// Its encoding does not depend on whether the field is addressable.
+	type Link struct {
+		URL url.URL `json:"url"`
+	}
+	u, err := url.Parse("https://example.com/path?q=1")
+	ASSERT_NIL(err, t)
+	l := Link{URL: *u}
+	for _, v := range []interface{}{l, &l, []Link{l}} {
+		s := mustSlice(velocypack.Marshal(v))
+		if s.IsArray() {
+			s = mustSlice(s.At(0))
+		}
+		urlSlice := mustSlice(s.Get("url"))
+		ASSERT_EQ(urlSlice.Type(), velocypack.Binary, t)
+		ASSERT_EQ(string(mustBytes(urlSlice.GetBinary())), u.String(), t)
+
+		var decoded Link
+		ASSERT_NIL(velocypack.Unmarshal(s, &decoded), t)
+		ASSERT_EQ(decoded.URL.String(), u.String(), t)
+	}
+}
+
+func TestDecoderBinaryUnmarshalerError(t *testing.T) {
+	s := velocypack.Slice(mustBytes(velocypack.Marshal([]byte{1, 2, 3})))
+	var v BinaryID
+	err := velocypack.Unmarshal(s, &v)
+	ASSERT_TRUE(err != nil, t)
+}
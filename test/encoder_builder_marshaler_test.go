@@ -0,0 +1,227 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+type BuilderPoint struct {
+	X, Y int
+}
+
+func (p BuilderPoint) MarshalVPackTo(b *velocypack.Builder) error {
+	if err := b.OpenArray(); err != nil {
+		return err
+	}
+	if err := b.AddValue(velocypack.NewIntValue(int64(p.X))); err != nil {
+		return err
+	}
+	if err := b.AddValue(velocypack.NewIntValue(int64(p.Y))); err != nil {
+		return err
+	}
+	return b.Close()
+}
+
+type BuilderPtrName struct {
+	Name string
+}
+
+func (n *BuilderPtrName) MarshalVPackTo(b *velocypack.Builder) error {
+	return b.AddValue(velocypack.NewStringValue("name:" + n.Name))
+}
+
+type BuilderFailing struct{}
+
+func (BuilderFailing) MarshalVPackTo(b *velocypack.Builder) error {
+	return errors.New("failing")
+}
+
+// BuilderTwoValues breaks the MarshalVPackTo contract by adding two values.
+type BuilderTwoValues struct{}
+
+func (BuilderTwoValues) MarshalVPackTo(b *velocypack.Builder) error {
+	if err := b.AddValue(velocypack.NewIntValue(1)); err != nil {
+		return err
+	}
+	return b.AddValue(velocypack.NewIntValue(2))
+}
+
+// BuilderNoValue breaks the MarshalVPackTo contract by adding nothing.
+type BuilderNoValue struct{}
+
+func (BuilderNoValue) MarshalVPackTo(b *velocypack.Builder) error {
+	return nil
+}
+
+// BuilderUnclosed breaks the MarshalVPackTo contract by leaving an array open.
+type BuilderUnclosed struct{}
+
+func (BuilderUnclosed) MarshalVPackTo(b *velocypack.Builder) error {
+	return b.OpenArray()
+}
+
+type BuilderContainer struct {
+	Point BuilderPoint
+	Name  BuilderPtrName
+	Ptr   *BuilderPoint
+}
+
+type IteratorPerson struct {
+	Name string
+	Age  int
+	Keys int
+}
+
+func (p *IteratorPerson) UnmarshalVPackFrom(it *velocypack.ObjectIterator) error {
+	for it.IsValid() {
+		key, err := it.Key(true)
+		if err != nil {
+			return err
+		}
+		value, err := it.Value()
+		if err != nil {
+			return err
+		}
+		k, err := key.GetString()
+		if err != nil {
+			return err
+		}
+		switch k {
+		case "name":
+			if p.Name, err = value.GetString(); err != nil {
+				return err
+			}
+		case "age":
+			age, err := value.GetInt()
+			if err != nil {
+				return err
+			}
+			p.Age = int(age)
+		}
+		p.Keys++
+		if err := it.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestEncoderBuilderMarshalerValue(t *testing.T) {
+	bytes, err := velocypack.Marshal(BuilderPoint{1, 2})
+	ASSERT_NIL(err, t)
+	s := velocypack.Slice(bytes)
+
+	ASSERT_EQ(s.Type(), velocypack.Array, t)
+	ASSERT_EQ(mustString(s.JSONString()), "[1,2]", t)
+}
+
+func TestEncoderBuilderMarshalerPointer(t *testing.T) {
+	bytes, err := velocypack.Marshal(&BuilderPtrName{Name: "foo"})
+	ASSERT_NIL(err, t)
+	s := velocypack.Slice(bytes)
+
+	ASSERT_EQ(s.Type(), velocypack.String, t)
+	ASSERT_EQ(mustString(s.JSONString()), `"name:foo"`, t)
+}
+
+func TestEncoderBuilderMarshalerStructFields(t *testing.T) {
+	c := &BuilderContainer{
+		Point: BuilderPoint{3, 4},
+		Name:  BuilderPtrName{Name: "bar"},
+	}
+	bytes, err := velocypack.Marshal(c)
+	ASSERT_NIL(err, t)
+	s := velocypack.Slice(bytes)
+
+	ASSERT_EQ(s.Type(), velocypack.Object, t)
+	ASSERT_EQ(mustString(s.JSONString()), `{"Name":"name:bar","Point":[3,4],"Ptr":null}`, t)
+}
+
+func TestEncoderBuilderMarshalerError(t *testing.T) {
+	_, err := velocypack.Marshal(BuilderFailing{})
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsMarshaler, t)(err)
+	ASSERT_TRUE(strings.Contains(err.Error(), "MarshalVPackTo"), t)
+}
+
+func TestEncoderBuilderMarshalerOneValue(t *testing.T) {
+	type fields struct {
+		A BuilderTwoValues
+		B BuilderNoValue
+		C BuilderUnclosed
+	}
+	tests := []interface{}{
+		BuilderTwoValues{},
+		[]BuilderTwoValues{{}},
+		[]BuilderNoValue{{}, {}},
+		[]BuilderUnclosed{{}},
+		struct{ A BuilderTwoValues }{},
+		struct{ B BuilderNoValue }{},
+		struct{ C BuilderUnclosed }{},
+		&fields{},
+		map[string]BuilderTwoValues{"a": {}},
+	}
+	for _, v := range tests {
+		_, err := velocypack.Marshal(v)
+		ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsMarshaler, t)(err)
+	}
+}
+
+func TestDecoderIteratorUnmarshaler(t *testing.T) {
+	s := velocypack.Slice(mustBytes(velocypack.Marshal(map[string]interface{}{
+		"name":  "Jan",
+		"age":   42,
+		"other": true,
+	})))
+
+	var p IteratorPerson
+	ASSERT_NIL(velocypack.Unmarshal(s, &p), t)
+	ASSERT_EQ(p.Name, "Jan", t)
+	ASSERT_EQ(p.Age, 42, t)
+	ASSERT_EQ(p.Keys, 3, t)
+}
+
+func TestDecoderIteratorUnmarshalerField(t *testing.T) {
+	s := velocypack.Slice(mustBytes(velocypack.Marshal(map[string]interface{}{
+		"person": map[string]interface{}{"name": "Piet"},
+	})))
+
+	var v struct {
+		Person IteratorPerson `json:"person"`
+	}
+	ASSERT_NIL(velocypack.Unmarshal(s, &v), t)
+	ASSERT_EQ(v.Person.Name, "Piet", t)
+	ASSERT_EQ(v.Person.Keys, 1, t)
+}
+
+func TestDecoderIteratorUnmarshalerNonObject(t *testing.T) {
+	s := velocypack.Slice(mustBytes(velocypack.Marshal([]int{1, 2})))
+
+	var p IteratorPerson
+	err := velocypack.Unmarshal(s, &p)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsInvalidType, t)(err)
+}
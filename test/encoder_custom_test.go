@@ -266,3 +266,14 @@ func TestEncoderCustomJSONVPACKStruct1(t *testing.T) {
 	ASSERT_EQ(s.Type(), velocypack.String, t)
 	ASSERT_EQ(`"Hello VPACK, goodbye JSON"`, mustString(s.JSONString()), t)
 }
+
+type CustomFailingStruct1 struct{}
+
+func (cs CustomFailingStruct1) MarshalVPack() (velocypack.Slice, error) {
+	return nil, fmt.Errorf("failing")
+}
+
+func TestEncoderCustomMarshalerError(t *testing.T) {
+	_, err := velocypack.Marshal([]interface{}{1, CustomFailingStruct1{}})
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsMarshaler, t)(err)
+}
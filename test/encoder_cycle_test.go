@@ -0,0 +1,76 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+type CycleNode struct {
+	Name string
+	Next *CycleNode
+}
+
+type CycleMap map[string]interface{}
+
+func TestEncoderCyclePointer(t *testing.T) {
+	a := &CycleNode{Name: "a"}
+	b := &CycleNode{Name: "b", Next: a}
+	a.Next = b
+	_, err := velocypack.Marshal(a)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnsupportedValue, t)(err)
+}
+
+func TestEncoderCycleMap(t *testing.T) {
+	m := CycleMap{}
+	m["self"] = m
+	_, err := velocypack.Marshal(m)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnsupportedValue, t)(err)
+}
+
+func TestEncoderCycleSlice(t *testing.T) {
+	s := make([]interface{}, 1)
+	s[0] = s
+	_, err := velocypack.Marshal(s)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnsupportedValue, t)(err)
+}
+
+func TestEncoderNoCycleSharedPointer(t *testing.T) {
+	// The same pointer used multiple times is not a cycle.
+	shared := &CycleNode{Name: "shared"}
+	input := []*CycleNode{shared, shared, {Name: "x", Next: shared}}
+	s := mustSlice(velocypack.Marshal(input))
+	ASSERT_EQ(`[{"Name":"shared","Next":null},{"Name":"shared","Next":null},{"Name":"x","Next":{"Name":"shared","Next":null}}]`, mustString(s.JSONString()), t)
+}
+
+func TestEncoderNoCycleDeepList(t *testing.T) {
+	// Deep, but acyclic structures are encoded normally.
+	var head *CycleNode
+	for i := 0; i < 2000; i++ {
+		head = &CycleNode{Name: "n", Next: head}
+	}
+	_, err := velocypack.Marshal(head)
+	ASSERT_NIL(err, t)
+}
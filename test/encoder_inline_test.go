@@ -0,0 +1,125 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+type InlineBase struct {
+	Key string `json:"_key"`
+	Rev string `json:"_rev,omitempty"`
+}
+
+type InlineDocument struct {
+	Base  InlineBase `json:",inline"`
+	Name  string     `json:"name"`
+	Other InlineBase `json:"other"`
+}
+
+type InlinePtrDocument struct {
+	Base *InlineBase `json:"base,inline"`
+	Name string      `json:"name"`
+}
+
+type InlineMapDocument struct {
+	Name  string                 `json:"name"`
+	Extra map[string]interface{} `json:",inline"`
+}
+
+type RestDocument struct {
+	Key  string                         `json:"_key"`
+	Name string                         `json:"name"`
+	Rest map[string]velocypack.RawSlice `json:",rest"`
+}
+
+func TestInlineStruct(t *testing.T) {
+	input := InlineDocument{Base: InlineBase{Key: "k1"}, Name: "foo", Other: InlineBase{Key: "k2"}}
+	s := mustSlice(velocypack.Marshal(input))
+	ASSERT_EQ(`{"_key":"k1","name":"foo","other":{"_key":"k2"}}`, mustString(s.JSONString()), t)
+
+	var v InlineDocument
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(v, input, t)
+}
+
+func TestInlineStructPointer(t *testing.T) {
+	s := mustSlice(velocypack.Marshal(InlinePtrDocument{Name: "foo"}))
+	ASSERT_EQ(`{"name":"foo"}`, mustString(s.JSONString()), t)
+
+	s = mustSlice(velocypack.ParseJSONFromString(`{"_key":"k1","_rev":"r1","name":"foo"}`))
+	var v InlinePtrDocument
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(*v.Base, InlineBase{Key: "k1", Rev: "r1"}, t)
+	ASSERT_EQ(v.Name, "foo", t)
+}
+
+func TestInlineMap(t *testing.T) {
+	input := InlineMapDocument{Name: "foo", Extra: map[string]interface{}{"a": "b", "name": "ignored"}}
+	s := mustSlice(velocypack.Marshal(input))
+	ASSERT_EQ(`{"a":"b","name":"foo"}`, mustString(s.JSONString()), t)
+
+	s = mustSlice(velocypack.ParseJSONFromString(`{"name":"foo","x":1,"y":[true]}`))
+	var v InlineMapDocument
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(v.Name, "foo", t)
+	ASSERT_EQ(v.Extra, map[string]interface{}{"x": 1, "y": []interface{}{true}}, t)
+}
+
+func TestRestRoundTrip(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(`{"_key":"k1","name":"foo","nested":{"a":[1,2]},"flag":false,"nothing":null}`))
+	var v RestDocument
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(v.Key, "k1", t)
+	ASSERT_EQ(v.Name, "foo", t)
+	ASSERT_EQ(len(v.Rest), 3, t)
+	ASSERT_EQ(`{"a":[1,2]}`, mustString(velocypack.Slice(v.Rest["nested"]).JSONString()), t)
+
+	// Modify and write back
+	v.Name = "bar"
+	out := mustSlice(velocypack.Marshal(v))
+	ASSERT_EQ(`{"_key":"k1","flag":false,"name":"bar","nested":{"a":[1,2]},"nothing":null}`, mustString(out.JSONString()), t)
+}
+
+func TestRestNoUnknownAttributes(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(`{"_key":"k1"}`))
+	var v RestDocument
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_TRUE(v.Rest == nil, t)
+	ASSERT_EQ(`{"_key":"k1","name":""}`, mustString(mustSlice(velocypack.Marshal(v)).JSONString()), t)
+}
+
+func TestRestDeterministic(t *testing.T) {
+	rest := make(map[string]velocypack.RawSlice)
+	for i := 0; i < 50; i++ {
+		rest[fmt.Sprintf("attr%d", i)] = velocypack.RawSlice(mustSlice(velocypack.Marshal(i)))
+	}
+	v := RestDocument{Key: "k1", Rest: rest}
+	expected := mustSlice(velocypack.Marshal(v))
+	for i := 0; i < 10; i++ {
+		ASSERT_EQ(mustSlice(velocypack.Marshal(v)), expected, t)
+	}
+}
@@ -23,6 +23,7 @@
 package test
 
 import (
+	"bytes"
 	"testing"
 
 	velocypack "github.com/arangodb/go-velocypack"
@@ -262,3 +263,71 @@ func TestEncoderMapRandomIterator(t *testing.T) {
 	ASSERT_EQ(`{"age":34}`, outputB, t)
 	ASSERT_EQ(`{"_key":"1246","_rev":"_U4_BZxm---","age":34}`, output, t)
 }
+
+type MapPairKey struct {
+	From string
+	To   int
+}
+
+func TestEncoderMapStructKeyUnsupported(t *testing.T) {
+	_, err := velocypack.Marshal(map[MapPairKey]string{{From: "a", To: 1}: "x"})
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnsupportedType, t)(err)
+}
+
+func TestEncoderMapStructKeyPairs(t *testing.T) {
+	input := map[MapPairKey]string{
+		{From: "b", To: 1}: "x",
+		{From: "a", To: 2}: "y",
+	}
+	opts := velocypack.EncoderOptions{MapKeyPairs: true}
+	s := mustSlice(velocypack.Marshal(input, opts))
+
+	ASSERT_EQ(s.Type(), velocypack.Array, t)
+	ASSERT_EQ(`[[{"From":"a","To":2},"y"],[{"From":"b","To":1},"x"]]`, mustString(s.JSONString()), t)
+
+	var v map[MapPairKey]string
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(v, input, t)
+}
+
+func TestEncoderMapArrayKeyPairs(t *testing.T) {
+	input := map[[2]int]bool{{1, 2}: true, {3, 4}: false}
+	s := mustSlice(velocypack.Marshal(input, velocypack.EncoderOptions{MapKeyPairs: true}))
+	ASSERT_EQ(`[[[1,2],true],[[3,4],false]]`, mustString(s.JSONString()), t)
+
+	var v map[[2]int]bool
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(v, input, t)
+}
+
+func TestEncoderMapKeyPairsNested(t *testing.T) {
+	type container struct {
+		Table map[MapPairKey]int
+		Names map[string]int
+	}
+	input := container{
+		Table: map[MapPairKey]int{{From: "a", To: 1}: 7},
+		Names: map[string]int{"n": 1},
+	}
+	var buf bytes.Buffer
+	e := velocypack.NewEncoder(&buf, velocypack.EncoderOptions{MapKeyPairs: true})
+	must(e.Encode(input))
+
+	s := velocypack.Slice(buf.Bytes())
+	ASSERT_EQ(`{"Names":{"n":1},"Table":[[{"From":"a","To":1},7]]}`, mustString(s.JSONString()), t)
+
+	var v container
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(v, input, t)
+}
+
+func TestDecoderMapPairsInvalid(t *testing.T) {
+	var v map[MapPairKey]string
+	s := mustSlice(velocypack.ParseJSONFromString(`[[{"From":"a","To":1}]]`))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnmarshalType, t)(velocypack.Unmarshal(s, &v))
+
+	var m map[string]int
+	s = mustSlice(velocypack.ParseJSONFromString(`[["a",1],["b",2]]`))
+	must(velocypack.Unmarshal(s, &m))
+	ASSERT_EQ(m, map[string]int{"a": 1, "b": 2}, t)
+}
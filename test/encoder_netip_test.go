@@ -0,0 +1,54 @@
+//go:build go1.18
+// +build go1.18
+
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"net/netip"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+func TestEncoderNetipAddrDefault(t *testing.T) {
+	// netip.Addr implements both encoding.TextMarshaler and encoding.BinaryMarshaler,
+	// so it is encoded as Binary unless PreferTextMarshaler is set.
+	addr := netip.MustParseAddr("192.168.1.2")
+	s := mustSlice(velocypack.Marshal(addr))
+	ASSERT_EQ(s.Type(), velocypack.Binary, t)
+	ASSERT_EQ(mustBytes(s.GetBinary()), addr.AsSlice(), t)
+
+	var v netip.Addr
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(v, addr, t)
+
+	s = mustSlice(velocypack.Marshal(addr, velocypack.EncoderOptions{PreferTextMarshaler: true}))
+	ASSERT_EQ(s.Type(), velocypack.String, t)
+	ASSERT_EQ(mustString(s.GetString()), "192.168.1.2", t)
+
+	v = netip.Addr{}
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(v, addr, t)
+}
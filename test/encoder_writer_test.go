@@ -24,6 +24,9 @@ package test
 
 import (
 	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	velocypack "github.com/arangodb/go-velocypack"
@@ -93,3 +96,107 @@ func TestEncoderWriterStruct1(t *testing.T) {
 		ASSERT_EQ(v, expected, t)
 	}
 }
+
+func TestEncoderWriterArrayStream(t *testing.T) {
+	f, err := ioutil.TempFile("", "vpack")
+	ASSERT_NIL(err, t)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	e := velocypack.NewEncoder(f)
+	must(e.OpenArray())
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsEncoderArrayAlreadyOpen, t)(e.OpenArray())
+	for i := 0; i < 1000; i++ {
+		must(e.Encode(Struct1{Field1: i}))
+	}
+	must(e.Close())
+	// Values after the array are written as usual
+	must(e.Encode("end"))
+
+	data, err := ioutil.ReadFile(f.Name())
+	ASSERT_NIL(err, t)
+	d := velocypack.NewDecoder(bytes.NewReader(data))
+
+	s := mustSlice(d.Token())
+	ASSERT_EQ(s.Type(), velocypack.Array, t)
+	ASSERT_EQ(velocypack.ValueLength(1000), mustLength(s.Length()), t)
+	ASSERT_EQ(mustLength(s.ByteSize()), velocypack.ValueLength(len(s)), t)
+	var v []Struct1
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(len(v), 1000, t)
+	for i, x := range v {
+		ASSERT_EQ(x, Struct1{Field1: i}, t)
+	}
+	ASSERT_EQ(int64(500), mustInt(mustSlice(mustSlice(s.At(500)).Get("Field1")).GetInt()), t)
+
+	var end string
+	must(d.Decode(&end))
+	ASSERT_EQ(end, "end", t)
+}
+
+func TestEncoderWriterArrayStreamEmpty(t *testing.T) {
+	f, err := ioutil.TempFile("", "vpack")
+	ASSERT_NIL(err, t)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	e := velocypack.NewEncoder(f)
+	must(e.OpenArray())
+	must(e.Close())
+
+	data, err := ioutil.ReadFile(f.Name())
+	ASSERT_NIL(err, t)
+	ASSERT_EQ(velocypack.Slice(data), velocypack.Slice{0x01}, t)
+}
+
+func TestEncoderWriterArrayBuffered(t *testing.T) {
+	var buf bytes.Buffer
+	e := velocypack.NewEncoder(&buf)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderNeedOpenArray, t)(e.Close())
+	must(e.OpenArray())
+	for i := 0; i < 10; i++ {
+		must(e.Encode(i))
+	}
+	must(e.Close())
+
+	var v []int
+	must(velocypack.Unmarshal(buf.Bytes(), &v))
+	ASSERT_EQ(v, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, t)
+}
+
+// noSeekWriter is a WriteSeeker that cannot seek, like a pipe.
+type noSeekWriter struct {
+	bytes.Buffer
+}
+
+func (w *noSeekWriter) Seek(offset int64, whence int) (int64, error) {
+	return 0, fmt.Errorf("illegal seek")
+}
+
+func TestEncoderWriterArrayNoSeek(t *testing.T) {
+	var w noSeekWriter
+	e := velocypack.NewEncoder(&w)
+	must(e.OpenArray())
+	for i := 0; i < 10; i++ {
+		must(e.Encode(i))
+	}
+	must(e.Close())
+
+	var v []int
+	must(velocypack.Unmarshal(w.Bytes(), &v))
+	ASSERT_EQ(v, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, t)
+}
+
+func TestEncoderWriterArrayBufferedError(t *testing.T) {
+	var buf bytes.Buffer
+	e := velocypack.NewEncoder(&buf)
+	must(e.OpenArray())
+	must(e.Encode(1))
+	// Fails after the object and its first attribute have been added
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnsupportedType, t)(e.Encode(map[string]interface{}{"a": 1, "b": make(chan int)}))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsMarshaler, t)(e.Encode([]interface{}{"x", CustomFailingStruct1{}}))
+	must(e.Encode(map[string]int{"c": 2}))
+	must(e.Close())
+
+	ASSERT_EQ(mustString(velocypack.Slice(buf.Bytes()).JSONString()), `[1,{"c":2}]`, t)
+}
@@ -0,0 +1,79 @@
+//go:build go1.18
+// +build go1.18
+
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+func TestGenericsUnmarshalAs(t *testing.T) {
+	s := mustSlice(velocypack.Marshal(Struct1{Field1: 42}))
+	v, err := velocypack.UnmarshalAs[Struct1](s)
+	ASSERT_NIL(err, t)
+	ASSERT_EQ(v, Struct1{Field1: 42}, t)
+
+	_, err = velocypack.UnmarshalAs[int](mustSlice(velocypack.Marshal("foo")))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnmarshalType, t)(err)
+}
+
+func TestGenericsGetAs(t *testing.T) {
+	s := mustSlice(velocypack.ParseJSONFromString(`{"a":{"b":[1,2,3],"c":"foo"}}`))
+
+	b, err := velocypack.GetAs[[]int](s, "a", "b")
+	ASSERT_NIL(err, t)
+	ASSERT_EQ(b, []int{1, 2, 3}, t)
+
+	c, err := velocypack.GetAs[string](s, "a", "c")
+	ASSERT_NIL(err, t)
+	ASSERT_EQ(c, "foo", t)
+
+	missing, err := velocypack.GetAs[string](s, "a", "missing")
+	ASSERT_NIL(err, t)
+	ASSERT_EQ(missing, "", t)
+
+	_, err = velocypack.GetAs[string](s, "a", "c", "d")
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsInvalidType, t)(err)
+}
+
+func TestGenericsTypedArrayIterator(t *testing.T) {
+	s := mustSlice(velocypack.Marshal([]Struct1{{Field1: 1}, {Field1: 2}, {Field1: 3}}))
+	it, err := velocypack.NewTypedArrayIterator[Struct1](s)
+	ASSERT_NIL(err, t)
+
+	var result []Struct1
+	for it.IsValid() {
+		v, err := it.Value()
+		ASSERT_NIL(err, t)
+		result = append(result, v)
+		must(it.Next())
+	}
+	ASSERT_EQ(result, []Struct1{{Field1: 1}, {Field1: 2}, {Field1: 3}}, t)
+
+	_, err = velocypack.NewTypedArrayIterator[int](mustSlice(velocypack.Marshal(1)))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsInvalidType, t)(err)
+}
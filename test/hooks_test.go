@@ -0,0 +1,135 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+type hookHost struct {
+	Name string  `json:"name"`
+	IP   net.IP  `json:"ip"`
+	Alt  *net.IP `json:"alt,omitempty"`
+}
+
+var (
+	ipType         = reflect.TypeOf(net.IP{})
+	hookEncoderMap = map[reflect.Type]func(*velocypack.Builder, reflect.Value) error{
+		ipType: func(b *velocypack.Builder, v reflect.Value) error {
+			ip := v.Interface().(net.IP)
+			return b.AddValue(velocypack.NewBinaryValue(ip.To16()))
+		},
+	}
+	hookDecoderMap = map[reflect.Type]func(velocypack.Slice, reflect.Value) error{
+		ipType: func(s velocypack.Slice, v reflect.Value) error {
+			data, err := s.GetBinary()
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(net.IP(append([]byte{}, data...))))
+			return nil
+		},
+	}
+)
+
+func TestEncoderTypeHook(t *testing.T) {
+	ip := net.ParseIP("10.0.0.1")
+	opts := velocypack.EncoderOptions{TypeEncoders: hookEncoderMap}
+	s := mustSlice(velocypack.Marshal(hookHost{Name: "db", IP: ip, Alt: &ip}, opts))
+
+	ipSlice := mustSlice(s.Get("ip"))
+	ASSERT_EQ(velocypack.Binary, ipSlice.Type(), t)
+	ASSERT_EQ([]byte(ip.To16()), mustBytes(ipSlice.GetBinary()), t)
+	ASSERT_EQ(velocypack.Binary, mustSlice(s.Get("alt")).Type(), t)
+
+	// Without hooks, net.IP is encoded as text.
+	s = mustSlice(velocypack.Marshal(hookHost{Name: "db", IP: ip}))
+	ASSERT_EQ(`{"ip":"10.0.0.1","name":"db"}`, mustString(s.JSONString()), t)
+}
+
+func TestEncoderTypeHookError(t *testing.T) {
+	opts := velocypack.EncoderOptions{
+		TypeEncoders: map[reflect.Type]func(*velocypack.Builder, reflect.Value) error{
+			ipType: func(b *velocypack.Builder, v reflect.Value) error {
+				return velocypack.InternalError
+			},
+		},
+	}
+	_, err := velocypack.Marshal([]net.IP{net.IPv4zero}, opts)
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsMarshaler, t)(err)
+}
+
+func TestEncoderTypeHookMaps(t *testing.T) {
+	// Each hook map must use its own encoders, even when an earlier map
+	// has been released and its memory reused.
+	for i := 0; i < 10; i++ {
+		n := int64(i)
+		opts := velocypack.EncoderOptions{
+			TypeEncoders: map[reflect.Type]func(*velocypack.Builder, reflect.Value) error{
+				ipType: func(b *velocypack.Builder, v reflect.Value) error {
+					return b.AddValue(velocypack.NewIntValue(n))
+				},
+			},
+		}
+		s := mustSlice(velocypack.Marshal([]net.IP{net.IPv4zero}, opts))
+		ASSERT_EQ(n, mustInt(mustSlice(s.At(0)).GetInt()), t)
+	}
+}
+
+func TestEncoderTypeHookReuse(t *testing.T) {
+	ip := net.ParseIP("10.0.0.2")
+	var buf bytes.Buffer
+	e := velocypack.NewEncoder(&buf, velocypack.EncoderOptions{TypeEncoders: hookEncoderMap})
+	for i := 0; i < 2; i++ {
+		must(e.Encode(hookHost{Name: "db", IP: ip}))
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+	for i := 0; i < 2; i++ {
+		s, err := velocypack.SliceFromReader(r)
+		if err != nil {
+			t.Fatalf("SliceFromReader failed: %v", err)
+		}
+		ASSERT_EQ(velocypack.Binary, mustSlice(s.Get("ip")).Type(), t)
+	}
+}
+
+func TestDecoderTypeHook(t *testing.T) {
+	ip := net.ParseIP("192.168.1.2")
+	s := mustSlice(velocypack.Marshal(hookHost{Name: "db", IP: ip, Alt: &ip}, velocypack.EncoderOptions{TypeEncoders: hookEncoderMap}))
+
+	var v hookHost
+	must(velocypack.Unmarshal(s, &v, velocypack.DecoderOptions{TypeDecoders: hookDecoderMap}))
+	ASSERT_EQ(v.Name, "db", t)
+	ASSERT_TRUE(v.IP.Equal(ip), t)
+	ASSERT_TRUE(v.Alt != nil && v.Alt.Equal(ip), t)
+
+	var direct net.IP
+	must(velocypack.Unmarshal(mustSlice(s.Get("ip")), &direct, velocypack.DecoderOptions{TypeDecoders: hookDecoderMap}))
+	ASSERT_TRUE(direct.Equal(ip), t)
+}
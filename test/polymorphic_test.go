@@ -0,0 +1,119 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+type PolyShape interface {
+	Area() float64
+}
+
+type PolyCircle struct {
+	Radius float64 `json:"radius"`
+}
+
+func (c PolyCircle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type PolyRect struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (r *PolyRect) Area() float64 { return r.Width * r.Height }
+
+type PolyTagged struct {
+	Kind string `json:"type"`
+}
+
+func (p PolyTagged) Area() float64 { return 0 }
+
+type PolyDrawing struct {
+	Main   PolyShape   `json:"main"`
+	Shapes []PolyShape `json:"shapes"`
+}
+
+func init() {
+	velocypack.RegisterPolymorphicType((*PolyShape)(nil), "type", "circle", PolyCircle{})
+	velocypack.RegisterPolymorphicType((*PolyShape)(nil), "type", "rect", &PolyRect{})
+	velocypack.RegisterPolymorphicType((*PolyShape)(nil), "type", "tagged", PolyTagged{})
+}
+
+func TestPolymorphicMarshal(t *testing.T) {
+	s := mustSlice(velocypack.Marshal(PolyCircle{Radius: 2}))
+	ASSERT_EQ(`{"radius":2,"type":"circle"}`, mustString(s.JSONString()), t)
+
+	s = mustSlice(velocypack.Marshal(&PolyRect{Width: 1, Height: 3}))
+	ASSERT_EQ(`{"height":3,"type":"rect","width":1}`, mustString(s.JSONString()), t)
+
+	// Type with a field named like the discriminator writes the field only.
+	s = mustSlice(velocypack.Marshal(PolyTagged{Kind: "custom"}))
+	ASSERT_EQ(`{"type":"custom"}`, mustString(s.JSONString()), t)
+}
+
+func TestPolymorphicRoundTrip(t *testing.T) {
+	input := PolyDrawing{
+		Main:   PolyCircle{Radius: 1},
+		Shapes: []PolyShape{&PolyRect{Width: 2, Height: 3}, PolyCircle{Radius: 4}},
+	}
+	s := mustSlice(velocypack.Marshal(input))
+
+	var v PolyDrawing
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(v.Main, PolyCircle{Radius: 1}, t)
+	ASSERT_EQ(len(v.Shapes), 2, t)
+	ASSERT_EQ(*v.Shapes[0].(*PolyRect), PolyRect{Width: 2, Height: 3}, t)
+	ASSERT_EQ(v.Shapes[1], PolyCircle{Radius: 4}, t)
+}
+
+func TestPolymorphicUnknown(t *testing.T) {
+	var v PolyShape
+	s := mustSlice(velocypack.ParseJSONFromString(`{"type":"triangle"}`))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnmarshalType, t)(velocypack.Unmarshal(s, &v))
+
+	s = mustSlice(velocypack.ParseJSONFromString(`{"radius":1}`))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnmarshalType, t)(velocypack.Unmarshal(s, &v))
+	ASSERT_NIL(v, t)
+}
+
+func TestPolymorphicRegisterInvalid(t *testing.T) {
+	expectPanic := func(f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic")
+			}
+		}()
+		f()
+	}
+	// Not a pointer to an interface
+	expectPanic(func() { velocypack.RegisterPolymorphicType(PolyCircle{}, "type", "x", PolyCircle{}) })
+	// Does not implement the interface
+	expectPanic(func() { velocypack.RegisterPolymorphicType((*PolyShape)(nil), "type", "x", PolyRect{}) })
+	// Different discriminator attribute
+	expectPanic(func() { velocypack.RegisterPolymorphicType((*PolyShape)(nil), "kind", "x", PolyCircle{}) })
+	// Name already taken
+	expectPanic(func() { velocypack.RegisterPolymorphicType((*PolyShape)(nil), "type", "circle", &PolyRect{}) })
+}
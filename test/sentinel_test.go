@@ -0,0 +1,91 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+func TestSentinelMarshal(t *testing.T) {
+	ASSERT_EQ(mustSlice(velocypack.Marshal(velocypack.MinKeySentinel{})), velocypack.MinKeySlice(), t)
+	ASSERT_EQ(mustSlice(velocypack.Marshal(velocypack.MaxKeySentinel{})), velocypack.MaxKeySlice(), t)
+	ASSERT_EQ(mustSlice(velocypack.Marshal(velocypack.IllegalSentinel{})), velocypack.IllegalSlice(), t)
+	_, err := velocypack.Marshal(velocypack.NoneSentinel{})
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsBuilderUnexpectedType, t)(err)
+}
+
+func TestSentinelRangeBounds(t *testing.T) {
+	type bounds struct {
+		Low  interface{} `json:"low"`
+		High interface{} `json:"high"`
+	}
+	s := mustSlice(velocypack.Marshal([]interface{}{"a", velocypack.MinKeySentinel{}, bounds{velocypack.MinKeySentinel{}, velocypack.MaxKeySentinel{}}}))
+
+	var v []interface{}
+	must(velocypack.Unmarshal(s, &v))
+	ASSERT_EQ(v, []interface{}{
+		"a",
+		velocypack.MinKeySentinel{},
+		map[string]interface{}{"low": velocypack.MinKeySentinel{}, "high": velocypack.MaxKeySentinel{}},
+	}, t)
+}
+
+func TestSentinelUnmarshalTyped(t *testing.T) {
+	var minKey velocypack.MinKeySentinel
+	must(velocypack.Unmarshal(velocypack.MinKeySlice(), &minKey))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsInvalidType, t)(velocypack.Unmarshal(velocypack.MaxKeySlice(), &minKey))
+
+	var maxKey velocypack.MaxKeySentinel
+	must(velocypack.Unmarshal(velocypack.MaxKeySlice(), &maxKey))
+
+	var illegal velocypack.IllegalSentinel
+	must(velocypack.Unmarshal(velocypack.IllegalSlice(), &illegal))
+
+	var none velocypack.NoneSentinel
+	must(velocypack.Unmarshal(velocypack.NoneSlice(), &none))
+
+	var i int
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsUnmarshalType, t)(velocypack.Unmarshal(velocypack.MinKeySlice(), &i))
+}
+
+func TestSentinelUnmarshalInterface(t *testing.T) {
+	tests := map[string]interface{}{
+		"min":     velocypack.MinKeySentinel{},
+		"max":     velocypack.MaxKeySentinel{},
+		"illegal": velocypack.IllegalSentinel{},
+		"none":    velocypack.NoneSentinel{},
+	}
+	slices := map[string]velocypack.Slice{
+		"min":     velocypack.MinKeySlice(),
+		"max":     velocypack.MaxKeySlice(),
+		"illegal": velocypack.IllegalSlice(),
+		"none":    velocypack.NoneSlice(),
+	}
+	for name, expected := range tests {
+		var v interface{}
+		must(velocypack.Unmarshal(slices[name], &v))
+		ASSERT_EQ(v, expected, t)
+	}
+}
@@ -0,0 +1,139 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+package test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	velocypack "github.com/arangodb/go-velocypack"
+)
+
+// buildPartsConcurrently builds the given number of parts in separate goroutines.
+func buildPartsConcurrently(n int, build func(b *velocypack.Builder, part int)) []velocypack.Slice {
+	parts := make([]velocypack.Slice, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var b velocypack.Builder
+			build(&b, i)
+			parts[i] = mustSlice(b.Slice())
+		}(i)
+	}
+	wg.Wait()
+	return parts
+}
+
+func TestAssembleArray(t *testing.T) {
+	tests := []struct {
+		Parts    []string
+		Expected string
+	}{
+		{[]string{}, `[]`},
+		{[]string{`[]`, `[]`}, `[]`},
+		{[]string{`[1,2,3]`}, `[1,2,3]`},
+		{[]string{`[1,2]`, `[]`, `[3]`}, `[1,2,3]`},
+		{[]string{`["a",{"b":[1,2]}]`, `[null,true,"some longer string"]`}, `["a",{"b":[1,2]},null,true,"some longer string"]`},
+	}
+	for _, test := range tests {
+		var parts []velocypack.Slice
+		for _, p := range test.Parts {
+			parts = append(parts, mustSlice(velocypack.ParseJSONFromString(p)))
+		}
+		s := mustSlice(velocypack.AssembleArray(parts...))
+		ASSERT_EQ(mustString(s.JSONString()), test.Expected, t)
+	}
+}
+
+func TestAssembleArrayLarge(t *testing.T) {
+	const parts, perPart = 8, 10000
+	slices := buildPartsConcurrently(parts, func(b *velocypack.Builder, part int) {
+		must(b.OpenArray())
+		for i := 0; i < perPart; i++ {
+			n := part*perPart + i
+			if n%3 == 0 {
+				must(b.AddString(strings.Repeat("x", n%50)))
+			} else {
+				must(b.AddInt(int64(n)))
+			}
+		}
+		must(b.Close())
+	})
+	s := mustSlice(velocypack.AssembleArray(slices...))
+	ASSERT_EQ(mustLength(s.Length()), velocypack.ValueLength(parts*perPart), t)
+
+	// Compare with the array built by a single builder
+	var b velocypack.Builder
+	must(b.OpenArray())
+	for n := 0; n < parts*perPart; n++ {
+		if n%3 == 0 {
+			must(b.AddString(strings.Repeat("x", n%50)))
+		} else {
+			must(b.AddInt(int64(n)))
+		}
+	}
+	must(b.Close())
+	ASSERT_EQ(s, mustSlice(b.Slice()), t)
+}
+
+func TestAssembleArrayEqualSizes(t *testing.T) {
+	// Members of equal size are assembled into an array without index table
+	a := mustSlice(velocypack.ParseJSONFromString(`[1,2,3]`))
+	s := mustSlice(velocypack.AssembleArray(a, a))
+	ASSERT_EQ(s[0], byte(0x02), t)
+	ASSERT_EQ(mustString(s.JSONString()), `[1,2,3,1,2,3]`, t)
+}
+
+func TestAssembleObject(t *testing.T) {
+	slices := buildPartsConcurrently(4, func(b *velocypack.Builder, part int) {
+		must(b.OpenObject())
+		for i := 0; i < 1000; i++ {
+			must(b.AddKeyInt(fmt.Sprintf("key%d", part*1000+i), int64(part*1000+i)))
+		}
+		must(b.Close())
+	})
+	s := mustSlice(velocypack.AssembleObject(slices...))
+	ASSERT_EQ(mustLength(s.Length()), velocypack.ValueLength(4000), t)
+	ASSERT_TRUE(s.IsSorted(), t)
+	for i := 0; i < 4000; i++ {
+		ASSERT_EQ(mustInt(mustSlice(s.Get(fmt.Sprintf("key%d", i))).GetInt()), int64(i), t)
+	}
+
+	s = mustSlice(velocypack.AssembleObject(
+		mustSlice(velocypack.ParseJSONFromString(`{"a":1,"c":{"d":[1,2]}}`)),
+		mustSlice(velocypack.ParseJSONFromString(`{"b":"x"}`)),
+	))
+	ASSERT_EQ(mustString(s.JSONString()), `{"a":1,"b":"x","c":{"d":[1,2]}}`, t)
+}
+
+func TestAssembleErrors(t *testing.T) {
+	a := mustSlice(velocypack.ParseJSONFromString(`[1,2]`))
+	o := mustSlice(velocypack.ParseJSONFromString(`{"a":1,"b":2}`))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsInvalidType, t)(velocypack.AssembleArray(a, o))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsInvalidType, t)(velocypack.AssembleObject(o, a))
+	ASSERT_VELOCYPACK_EXCEPTION(velocypack.IsDuplicateAttributeName, t)(velocypack.AssembleObject(o, mustSlice(velocypack.ParseJSONFromString(`{"c":1,"a":2}`))))
+}
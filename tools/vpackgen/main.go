@@ -0,0 +1,441 @@
+//
+// DISCLAIMER
+//
+// Copyright 2017 ArangoDB GmbH, Cologne, Germany
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Copyright holder is ArangoDB GmbH, Cologne, Germany
+//
+// Author Ewout Prangsma
+//
+
+// vpackgen generates MarshalVPack, MarshalVPackTo & UnmarshalVPack methods for struct types,
+// so these types can be encoded & decoded without going through reflection.
+// MarshalVPackTo adds the value directly to a builder, so a generated type
+// nested in another value (including another generated type) is encoded
+// without building an intermediate slice.
+//
+// Typical usage is through go generate:
+//
+//	//go:generate vpackgen -type=Request,Response
+//
+// The generated code is written to <file>_vpack.go next to the input file.
+// It follows the same `json` tag rules as velocypack.Marshal, except that
+// embedded structs and the `,string` option are not supported and that
+// attribute names are matched exactly (not case-insensitive) when decoding.
+// Fields of types other than strings, booleans, numbers and []byte are
+// encoded & decoded using velocypack.Marshal & velocypack.Unmarshal,
+// except that (pointers to) types generated in the same run are encoded
+// using their MarshalVPackTo method.
+// Since the generator only looks at the syntax of the input file,
+// `omitempty` is ignored for fields of named (non builtin) types,
+// except for pointers, slices and maps.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+var (
+	typeNames  = flag.String("type", "", "comma-separated list of type names; defaults to all struct types in the file")
+	outputName = flag.String("output", "", "output file name; defaults to <file>_vpack.go")
+)
+
+func main() {
+	flag.Parse()
+	fileName := os.Getenv("GOFILE")
+	if args := flag.Args(); len(args) > 0 {
+		fileName = args[0]
+	}
+	if fileName == "" {
+		log.Fatalln("Usage: vpackgen [-type T1,T2] [-output file] <file.go>")
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, nil, parser.ParseComments)
+	if err != nil {
+		log.Fatalf("Failed to parse %s: %v\n", fileName, err)
+	}
+
+	var names []string
+	if *typeNames != "" {
+		names = strings.Split(*typeNames, ",")
+	}
+	types, err := findStructTypes(file, names)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	g := &generator{generated: make(map[string]bool)}
+	for _, t := range types {
+		g.generated[t.name] = true
+	}
+	g.printf("// Code generated by vpackgen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", file.Name.Name)
+	g.printf("import velocypack \"github.com/arangodb/go-velocypack\"\n")
+	for _, t := range types {
+		if err := g.generate(t); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		log.Fatalf("Failed to format generated code: %v\n", err)
+	}
+	output := *outputName
+	if output == "" {
+		output = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + "_vpack.go"
+	}
+	if err := ioutil.WriteFile(output, src, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v\n", output, err)
+	}
+}
+
+// structType is a struct type declaration found in the input file.
+type structType struct {
+	name string
+	st   *ast.StructType
+}
+
+// findStructTypes returns the struct types with given names (or all struct types if names is empty)
+// in the order in which they are declared.
+func findStructTypes(file *ast.File, names []string) ([]structType, error) {
+	wanted := make(map[string]bool)
+	for _, n := range names {
+		wanted[strings.TrimSpace(n)] = true
+	}
+	var result []structType
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || (len(wanted) > 0 && !wanted[ts.Name.Name]) {
+				continue
+			}
+			result = append(result, structType{name: ts.Name.Name, st: st})
+			delete(wanted, ts.Name.Name)
+		}
+	}
+	for n := range wanted {
+		return nil, fmt.Errorf("struct type %s not found", n)
+	}
+	return result, nil
+}
+
+// fieldKind describes how a field is encoded by the generated code.
+type fieldKind int
+
+const (
+	kindOther fieldKind = iota // Use velocypack.Marshal/Unmarshal
+	kindString
+	kindBool
+	kindInt
+	kindUInt
+	kindDouble
+	kindBinary
+	kindPointer      // Other, but omitted when nil
+	kindSlice        // Other, but omitted when empty
+	kindGenerated    // Type for which code is generated, use MarshalVPackTo
+	kindGeneratedPtr // Pointer to a type for which code is generated
+)
+
+// genField is a single field of a struct type to generate code for.
+type genField struct {
+	goName    string
+	goType    string // Only set for basic types
+	name      string
+	omitEmpty bool
+	kind      fieldKind
+}
+
+var basicKinds = map[string]fieldKind{
+	"string":  kindString,
+	"bool":    kindBool,
+	"int":     kindInt,
+	"int8":    kindInt,
+	"int16":   kindInt,
+	"int32":   kindInt,
+	"int64":   kindInt,
+	"uint":    kindUInt,
+	"uint8":   kindUInt,
+	"uint16":  kindUInt,
+	"uint32":  kindUInt,
+	"uint64":  kindUInt,
+	"float32": kindDouble,
+	"float64": kindDouble,
+}
+
+// structFields returns the fields of the given struct type that are encoded.
+// Generated contains the names of all types for which code is generated.
+func structFields(t structType, generated map[string]bool) ([]genField, error) {
+	var result []genField
+	for _, f := range t.st.Fields.List {
+		if len(f.Names) == 0 {
+			return nil, fmt.Errorf("%s: embedded fields are not supported", t.name)
+		}
+		var tag string
+		if f.Tag != nil {
+			tag = reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		omitEmpty := false
+		for _, o := range strings.Split(opts, ",") {
+			switch o {
+			case "omitempty":
+				omitEmpty = true
+			case "string":
+				return nil, fmt.Errorf("%s: option ,string is not supported", t.name)
+			}
+		}
+		kind, goType := kindOf(f.Type, generated)
+		for _, n := range f.Names {
+			if !n.IsExported() {
+				continue
+			}
+			gf := genField{
+				goName:    n.Name,
+				goType:    goType,
+				name:      name,
+				omitEmpty: omitEmpty,
+				kind:      kind,
+			}
+			if gf.name == "" {
+				gf.name = n.Name
+			}
+			result = append(result, gf)
+		}
+	}
+	return result, nil
+}
+
+// kindOf returns the kind of the given field type and,
+// for basic types, the name of the type.
+func kindOf(expr ast.Expr, generated map[string]bool) (fieldKind, string) {
+	switch x := expr.(type) {
+	case *ast.Ident:
+		if k, found := basicKinds[x.Name]; found {
+			return k, x.Name
+		}
+		if generated[x.Name] {
+			return kindGenerated, ""
+		}
+	case *ast.ArrayType:
+		if x.Len == nil {
+			if elt, ok := x.Elt.(*ast.Ident); ok && (elt.Name == "byte" || elt.Name == "uint8") {
+				return kindBinary, ""
+			}
+			return kindSlice, ""
+		}
+	case *ast.MapType:
+		return kindSlice, ""
+	case *ast.StarExpr:
+		if elt, ok := x.X.(*ast.Ident); ok && generated[elt.Name] {
+			return kindGeneratedPtr, ""
+		}
+		return kindPointer, ""
+	case *ast.InterfaceType:
+		return kindPointer, ""
+	}
+	return kindOther, ""
+}
+
+// generator collects the generated source.
+type generator struct {
+	buf       bytes.Buffer
+	generated map[string]bool // Names of the types for which code is generated
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// generate creates MarshalVPack, MarshalVPackTo & UnmarshalVPack for the given type.
+func (g *generator) generate(t structType) error {
+	fields, err := structFields(t, g.generated)
+	if err != nil {
+		return err
+	}
+	g.generateMarshal(t, fields)
+	g.generateUnmarshal(t, fields)
+	return nil
+}
+
+func (g *generator) generateMarshal(t structType, fields []genField) {
+	g.printf("\n// MarshalVPack implements velocypack.Marshaler.\n")
+	g.printf("func (v %s) MarshalVPack() (velocypack.Slice, error) {\n", t.name)
+	g.printf("var b velocypack.Builder\n")
+	g.printf("if err := v.MarshalVPackTo(&b); err != nil {\nreturn nil, err\n}\n")
+	g.printf("return b.Slice()\n}\n")
+
+	g.printf("\n// MarshalVPackTo implements velocypack.BuilderMarshaler.\n")
+	g.printf("func (v %s) MarshalVPackTo(b *velocypack.Builder) error {\n", t.name)
+	g.printf("if err := b.OpenObject(); err != nil {\nreturn err\n}\n")
+	for _, f := range fields {
+		ref := "v." + f.goName
+		if f.omitEmpty {
+			switch f.kind {
+			case kindString:
+				g.printf("if %s != \"\" {\n", ref)
+			case kindBool:
+				g.printf("if %s {\n", ref)
+			case kindInt, kindUInt, kindDouble:
+				g.printf("if %s != 0 {\n", ref)
+			case kindBinary, kindSlice:
+				g.printf("if len(%s) != 0 {\n", ref)
+			case kindPointer, kindGeneratedPtr:
+				g.printf("if %s != nil {\n", ref)
+			default:
+				g.printf("{\n")
+			}
+		}
+		switch f.kind {
+		case kindString:
+			g.addKeyValue(f.name, fmt.Sprintf("velocypack.NewStringValue(%s)", convert("string", f.goType, ref)))
+		case kindBool:
+			g.addKeyValue(f.name, fmt.Sprintf("velocypack.NewBoolValue(%s)", ref))
+		case kindInt:
+			g.addKeyValue(f.name, fmt.Sprintf("velocypack.NewIntValue(%s)", convert("int64", f.goType, ref)))
+		case kindUInt:
+			g.addKeyValue(f.name, fmt.Sprintf("velocypack.NewUIntValue(%s)", convert("uint64", f.goType, ref)))
+		case kindDouble:
+			g.addKeyValue(f.name, fmt.Sprintf("velocypack.NewDoubleValue(%s)", convert("float64", f.goType, ref)))
+		case kindBinary:
+			g.printf("if %s == nil {\n", ref)
+			g.addKeyValue(f.name, "velocypack.NewNullValue()")
+			g.printf("} else {\n")
+			g.addKeyValue(f.name, fmt.Sprintf("velocypack.NewBinaryValue(%s)", ref))
+			g.printf("}\n")
+		case kindGenerated:
+			g.addMarshalVPackTo(f.name, ref)
+		case kindGeneratedPtr:
+			if f.omitEmpty {
+				// Already checked for nil
+				g.addMarshalVPackTo(f.name, ref)
+			} else {
+				g.printf("if %s == nil {\n", ref)
+				g.addKeyValue(f.name, "velocypack.NewNullValue()")
+				g.printf("} else {\n")
+				g.addMarshalVPackTo(f.name, ref)
+				g.printf("}\n")
+			}
+		default:
+			g.printf("if s, err := velocypack.Marshal(%s); err != nil {\nreturn err\n} else ", ref)
+			g.addKeyValue(f.name, "velocypack.NewSliceValue(s)")
+		}
+		if f.omitEmpty {
+			g.printf("}\n")
+		}
+	}
+	g.printf("return b.Close()\n}\n")
+}
+
+// convert returns expr converted from type from to type to.
+func convert(to, from, expr string) string {
+	if to == from {
+		return expr
+	}
+	return to + "(" + expr + ")"
+}
+
+func (g *generator) addKeyValue(name, value string) {
+	g.printf("if err := b.AddKeyValue(%q, %s); err != nil {\nreturn err\n}\n", name, value)
+}
+
+// addMarshalVPackTo adds the given key, followed by the value of a generated type added by its MarshalVPackTo method.
+func (g *generator) addMarshalVPackTo(name, ref string) {
+	g.printf("if err := b.AddValue(velocypack.NewStringValue(%q)); err != nil {\nreturn err\n}\n", name)
+	g.printf("if err := %s.MarshalVPackTo(b); err != nil {\nreturn err\n}\n", ref)
+}
+
+func (g *generator) generateUnmarshal(t structType, fields []genField) {
+	g.printf("\n// UnmarshalVPack implements velocypack.Unmarshaler.\n")
+	g.printf("func (v *%s) UnmarshalVPack(s velocypack.Slice) error {\n", t.name)
+	g.printf("if s.IsNull() {\nreturn nil\n}\n")
+	if len(fields) == 0 {
+		g.printf("return s.AssertType(velocypack.Object)\n}\n")
+		return
+	}
+	g.printf("it, err := velocypack.NewObjectIterator(s, true)\nif err != nil {\nreturn err\n}\n")
+	g.printf("for it.IsValid() {\n")
+	g.printf("k, err := it.Key(true)\nif err != nil {\nreturn err\n}\n")
+	g.printf("key, err := k.GetStringUTF8()\nif err != nil {\nreturn err\n}\n")
+	g.printf("value, err := it.Value()\nif err != nil {\nreturn err\n}\n")
+	g.printf("switch string(key) {\n")
+	for _, f := range fields {
+		ref := "v." + f.goName
+		g.printf("case %q:\n", f.name)
+		switch f.kind {
+		case kindString:
+			g.printf("if value.IsString() {\n")
+			g.printf("x, err := value.GetString()\nif err != nil {\nreturn err\n}\n")
+			g.printf("%s = %s\n", ref, convert(f.goType, "string", "x"))
+		case kindBool:
+			g.printf("if value.IsBool() {\n")
+			g.printf("x, err := value.GetBool()\nif err != nil {\nreturn err\n}\n")
+			g.printf("%s = x\n", ref)
+		case kindInt:
+			g.printf("if value.IsInteger() {\n")
+			g.printf("x, err := value.GetInt()\nif err != nil {\nreturn err\n}\n")
+			if f.goType != "int64" {
+				g.printf("if int64(%s(x)) != x {\nreturn velocypack.NumberOutOfRangeError\n}\n", f.goType)
+			}
+			g.printf("%s = %s\n", ref, convert(f.goType, "int64", "x"))
+		case kindUInt:
+			g.printf("if value.IsInteger() {\n")
+			g.printf("x, err := value.GetUInt()\nif err != nil {\nreturn err\n}\n")
+			if f.goType != "uint64" {
+				g.printf("if uint64(%s(x)) != x {\nreturn velocypack.NumberOutOfRangeError\n}\n", f.goType)
+			}
+			g.printf("%s = %s\n", ref, convert(f.goType, "uint64", "x"))
+		case kindDouble:
+			g.printf("if value.IsDouble() {\n")
+			g.printf("x, err := value.GetDouble()\nif err != nil {\nreturn err\n}\n")
+			g.printf("%s = %s\n", ref, convert(f.goType, "float64", "x"))
+		case kindBinary:
+			g.printf("if value.IsBinary() {\n")
+			g.printf("x, err := value.GetBinary()\nif err != nil {\nreturn err\n}\n")
+			g.printf("%s = append([]byte{}, x...)\n", ref)
+		}
+		if f.kind != kindOther && f.kind != kindPointer && f.kind != kindSlice && f.kind != kindGenerated && f.kind != kindGeneratedPtr {
+			g.printf("} else ")
+		}
+		g.printf("if err := velocypack.Unmarshal(value, &%s); err != nil {\nreturn err\n}\n", ref)
+	}
+	g.printf("}\n")
+	g.printf("if err := it.Next(); err != nil {\nreturn err\n}\n")
+	g.printf("}\nreturn nil\n}\n")
+}
@@ -188,9 +188,13 @@ func (v Value) binaryValue() []byte {
 }
 
 func (v Value) utcDateValue() int64 {
-	time := v.data.(time.Time)
-	sec := time.Unix()
-	nsec := int64(time.Nanosecond())
+	return utcDateMillis(v.data.(time.Time))
+}
+
+// utcDateMillis returns the given time as milliseconds since the Unix epoch.
+func utcDateMillis(t time.Time) int64 {
+	sec := t.Unix()
+	nsec := int64(t.Nanosecond())
 	return sec*1000 + nsec/1000000
 }
 